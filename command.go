@@ -0,0 +1,136 @@
+package flaq
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Command is a command line command owning its own set of flags,
+// and possibly a set of subcommands (eg. "tool remote add --name x").
+type Command struct {
+	// Name of the command, as typed on the command line.
+	// The root command name defaults to the program name.
+	Name string
+
+	// Description for the command, as it will appear in its parent help usage.
+	Description string
+
+	// Flags is the set of flags accepted by the command.
+	Flags FlagSet
+
+	commands []*Command
+	parent   *Command
+}
+
+// Add adds a subcommand.
+func (c *Command) Add(cmd *Command) {
+	cmd.parent = c
+	cmd.Flags.command = cmd
+	c.Flags.command = c
+	c.commands = append(c.commands, cmd)
+}
+
+// Commands returns the subcommands of the command.
+func (c *Command) Commands() []*Command {
+	return c.commands
+}
+
+// Parent returns the parent command, or nil for a root command.
+func (c *Command) Parent() *Command {
+	return c.parent
+}
+
+// Path returns the full command path, eg. "tool remote add".
+func (c *Command) Path() string {
+	if c.parent == nil {
+		if c.Name == "" {
+			return filepath.Base(os.Args[0])
+		}
+		return c.Name
+	}
+	return c.parent.Path() + " " + c.Name
+}
+
+// Usage returns the command help usage.
+func (c *Command) Usage() string {
+	c.Flags.command = c
+	return c.Flags.Usage()
+}
+
+// Completion writes a completion script for the given shell: bash, zsh, fish or powershell.
+func (c *Command) Completion(w io.Writer, shell string) error {
+	c.Flags.command = c
+	return c.Flags.Completion(w, shell)
+}
+
+// Parse parses the argument list, which should not include the command name.
+// Options seen before a subcommand name belong to the command, while the ones
+// seen after it are parsed by the subcommand, along with any persistent option
+// inherited from its ancestors. Parse returns the command that was selected,
+// whose Args method returns the remaining operands.
+func (c *Command) Parse(args []string) (*Command, error) {
+	return c.parse(args, 0)
+}
+
+// parse parses the arguments of the command, which start at the given
+// position of the original argument list.
+func (c *Command) parse(args []string, offset int) (*Command, error) {
+	c.Flags.command, c.Flags.offset = c, offset
+	if err := c.Flags.Parse(args); err != nil {
+		return c, err
+	}
+	operands := c.Flags.Args()
+	pos := offset + len(args) - len(operands)
+	if cmd := c.subcommand(); cmd != nil {
+		cmd.inherit(c)
+		return cmd.parse(operands[1:], pos+1)
+	}
+	if len(c.commands) > 0 && len(operands) > 0 {
+		return c, c.Flags.fail(&UnknownCommandError{Name: operands[0], Pos: pos, Suggestions: c.suggest(operands[0])})
+	}
+	return c, nil
+}
+
+// Args returns the remaining arguments once options have been parsed.
+func (c *Command) Args() []string {
+	return c.Flags.Args()
+}
+
+// subcommand returns the subcommand named by the first remaining operand,
+// or nil if there is none.
+func (c *Command) subcommand() *Command {
+	operands := c.Flags.Args()
+	if len(operands) == 0 {
+		return nil
+	}
+	for _, cmd := range c.commands {
+		if cmd.Name == operands[0] {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// inherit adds the persistent flags of the parent command. A flag already
+// defined on the command, or sharing a name with one of its flags, is skipped.
+// The output writers and exit function of the parent are used unless set.
+func (c *Command) inherit(parent *Command) {
+	if c.Flags.Output == nil {
+		c.Flags.Output = parent.Flags.Output
+	}
+	if c.Flags.ErrOutput == nil {
+		c.Flags.ErrOutput = parent.Flags.ErrOutput
+	}
+	if c.Flags.Exit == nil {
+		c.Flags.Exit = parent.Flags.Exit
+	}
+	for _, flag := range parent.Flags.flags {
+		if !flag.Persistent || c.Flags.hasFlag(flag) {
+			continue
+		}
+		// The flag is appended as is, since its value may already have been
+		// set by the parent: Add would record it as the default value.
+		c.Flags.flags = append(c.Flags.flags, flag)
+	}
+}
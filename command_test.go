@@ -0,0 +1,179 @@
+package flaq
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandParse(t *testing.T) {
+	var verbose bool
+	var name, url string
+
+	root := &Command{Name: "tool"}
+	root.Flags.Bool(&verbose, "verbose", "v", "", false)
+
+	remote := &Command{Name: "remote", Description: "manage remotes"}
+	add := &Command{Name: "add", Description: "add a remote"}
+	add.Flags.String(&name, "name", "n", "")
+	add.Flags.String(&url, "url", "", "")
+	remote.Add(add)
+	root.Add(remote)
+
+	cmd, err := root.Parse([]string{"-v", "remote", "add", "--name", "x", "op1", "--url=u"})
+	require.NoError(t, err)
+	require.Equal(t, add, cmd)
+	require.Equal(t, []string{"op1"}, cmd.Args())
+	require.True(t, verbose)
+	require.Equal(t, "x", name)
+	require.Equal(t, "u", url)
+	require.Equal(t, "tool remote add", cmd.Path())
+}
+
+func TestCommandParseWithoutSubcommand(t *testing.T) {
+	root := &Command{Name: "tool"}
+	root.Add(&Command{Name: "remote"})
+
+	cmd, err := root.Parse([]string{})
+	require.NoError(t, err)
+	require.Equal(t, root, cmd)
+}
+
+func TestCommandParseUnknownCommand(t *testing.T) {
+	root := &Command{Name: "tool"}
+	root.Add(&Command{Name: "remote"})
+
+	_, err := root.Parse([]string{"remot"})
+	require.EqualError(t, err, "unknown command remot, did you mean remote?")
+
+	_, err = root.Parse([]string{"list"})
+	require.EqualError(t, err, "unknown command list")
+}
+
+func TestCommandPersistentFlags(t *testing.T) {
+	var verbose, force bool
+
+	root := &Command{Name: "tool"}
+	root.Flags.Add(&Flag{
+		Long:       "verbose",
+		Short:      "v",
+		Value:      (*boolValue)(&verbose),
+		Persistent: true,
+	})
+	root.Flags.Bool(&force, "force", "f", "", false)
+
+	remote := &Command{Name: "remote"}
+	root.Add(remote)
+
+	cmd, err := root.Parse([]string{"remote", "-v"})
+	require.NoError(t, err)
+	require.Equal(t, remote, cmd)
+	require.True(t, verbose)
+
+	_, err = root.Parse([]string{"remote", "--force"})
+	require.EqualError(t, err, "unknown option --force")
+}
+
+func TestCommandChangedPersistentFlags(t *testing.T) {
+	var verbose, force bool
+
+	root := &Command{Name: "tool"}
+	root.Flags.Add(&Flag{Long: "verbose", Short: "v", Value: (*boolValue)(&verbose), Persistent: true})
+	sub := &Command{Name: "sub"}
+	sub.Flags.Bool(&force, "force", "f", "", false)
+	root.Add(sub)
+
+	cmd, err := root.Parse([]string{"-v", "sub", "--force", "-v"})
+	require.NoError(t, err)
+	require.Equal(t, sub, cmd)
+	require.True(t, cmd.Flags.Changed("verbose"))
+	require.Equal(t, 2, cmd.Flags.Occurrences("v"))
+
+	var visited []string
+	cmd.Flags.Visit(func(flag *Flag) {
+		visited = append(visited, flag.Long)
+	})
+	require.Equal(t, []string{"verbose", "force"}, visited)
+
+	cmd, err = root.Parse([]string{"sub"})
+	require.NoError(t, err)
+	require.False(t, cmd.Flags.Changed("verbose"))
+}
+
+func TestCommandUsagePersistentFlagSet(t *testing.T) {
+	var out bytes.Buffer
+	var token string
+
+	root := &Command{Name: "tool"}
+	root.Flags.Output = &out
+	root.Flags.Add(&Flag{Long: "token", Value: (*stringValue)(&token), Arg: &FlagArg{Name: "string"}, Description: "tok", Persistent: true})
+	root.Add(&Command{Name: "remote"})
+
+	_, err := root.Parse([]string{"--token", "secret", "remote", "-h"})
+	require.Equal(t, ErrHelp, err)
+	require.Contains(t, out.String(), "--token <string>")
+	require.NotContains(t, out.String(), "secret")
+	require.NotContains(t, root.Usage(), "secret")
+}
+
+func TestCommandRequiredPersistentFlag(t *testing.T) {
+	for _, args := range [][]string{
+		{"sub", "--token", "x"},
+		{"--token", "x", "sub"},
+	} {
+		var token string
+
+		root := &Command{Name: "tool"}
+		root.Flags.Add(&Flag{
+			Long:       "token",
+			Value:      (*stringValue)(&token),
+			Arg:        &FlagArg{Name: "string"},
+			Required:   true,
+			Persistent: true,
+		})
+		sub := &Command{Name: "sub"}
+		root.Add(sub)
+
+		cmd, err := root.Parse(args)
+		require.NoError(t, err, args)
+		require.Equal(t, sub, cmd)
+		require.Equal(t, "x", token)
+	}
+
+	root := &Command{Name: "tool"}
+	root.Flags.Add(&Flag{Long: "token", Value: new(stringValue), Required: true, Persistent: true})
+	root.Add(&Command{Name: "sub"})
+
+	_, err := root.Parse([]string{"sub"})
+	require.EqualError(t, err, "missing required option --token")
+}
+
+func TestCommandUsage(t *testing.T) {
+	var name string
+
+	root := &Command{Name: "tool"}
+	remote := &Command{Name: "remote", Description: "manage remotes"}
+	add := &Command{Name: "add", Description: "add a remote"}
+	add.Flags.String(&name, "name", "n", "remote name")
+	remote.Add(add)
+	root.Add(remote)
+	root.Add(&Command{Name: "status", Description: "show status"})
+
+	expectedUsage := `Usage: tool [options] <command>
+
+Options
+
+Commands
+  remote   manage remotes
+  status   show status
+`
+	require.Equal(t, expectedUsage, root.Usage())
+
+	expectedUsage = `Usage: tool remote add [options]
+
+Options
+  -n, --name <string>   remote name
+`
+	require.Equal(t, expectedUsage, add.Usage())
+}
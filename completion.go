@@ -0,0 +1,301 @@
+package flaq
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// completeArg is the hidden first argument completion scripts call the program
+// with, followed by the words typed so far, the last one being the word to complete.
+const completeArg = "__complete"
+
+// Completion directives, printed as the last line of the completion candidates
+// to tell completion scripts how to complete the word.
+const (
+	completeDefault = 0
+	completeFiles   = 1 // Fall back to file path completion.
+)
+
+// CompleteFunc returns completion candidates for a partially typed word. The FlagSet
+// has already parsed the words typed before, so that candidates can depend on them.
+// Candidates not starting with word are ignored.
+type CompleteFunc func(word string, flags *FlagSet) []string
+
+// Completion writes a completion script for the given shell: bash, zsh, fish or powershell.
+func Completion(w io.Writer, shell string) error {
+	return flags.Completion(w, shell)
+}
+
+// Completion writes a completion script for the given shell: bash, zsh, fish or powershell.
+// Scripts call the program back with a hidden __complete argument to get completion
+// candidates, which are computed from the registered flags.
+func (f *FlagSet) Completion(w io.Writer, shell string) error {
+	tmpl, ok := completionScripts[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %s", shell)
+	}
+	name := f.program()
+	return tmpl.Execute(w, map[string]string{
+		"Name":     name,
+		"Function": regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(name, "_"),
+	})
+}
+
+// printCompletion prints the completion candidates for the words typed so far,
+// followed by the completion directive.
+func (f *FlagSet) printCompletion(words []string) {
+	candidates, directive := f.complete(words)
+	w := f.output()
+	for _, candidate := range candidates {
+		fmt.Fprintln(w, candidate)
+	}
+	fmt.Fprintf(w, ":%d\n", directive)
+}
+
+// complete returns the completion candidates for the last word, along with a
+// completion directive. The preceding words are parsed, on a best-effort basis,
+// for completion functions to see them, and scanned to know whether the word to
+// complete is an option, an option argument, or an operand.
+func (f *FlagSet) complete(words []string) ([]string, int) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	words, word := words[:len(words)-1], words[len(words)-1]
+
+	f.args, f.argc = append([]string(nil), words...), len(words)
+	for {
+		if seen, err := f.parseOne(); !seen || err != nil {
+			break
+		}
+	}
+
+	operands := false
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		switch {
+		case w == "--":
+			operands = true
+		case operands || len(w) < 2 || w[0] != '-':
+			if f.command == nil || len(f.command.commands) == 0 {
+				continue
+			}
+			for _, cmd := range f.command.commands {
+				if cmd.Name == w {
+					cmd.inherit(f.command)
+					return cmd.Flags.complete(append(words[i+1:], word))
+				}
+			}
+			return nil, completeDefault
+		case w[1] == '-':
+			if flag := f.Lookup(w[2:]); flag != nil && flag.Arg != nil && flag.Arg.Default == "" {
+				if i == len(words)-1 {
+					return f.completeFlagArg(flag, word, "")
+				}
+				i++
+			}
+		default:
+			for j := 1; j < len(w); j++ {
+				flag := f.Lookup(w[j : j+1])
+				if flag == nil || flag.Arg == nil {
+					continue
+				}
+				if j == len(w)-1 && flag.Arg.Default == "" {
+					if i == len(words)-1 {
+						return f.completeFlagArg(flag, word, "")
+					}
+					i++
+				}
+				break
+			}
+		}
+	}
+
+	if !operands && strings.HasPrefix(word, "--") {
+		if i := strings.IndexByte(word, '='); i >= 0 {
+			if flag := f.Lookup(word[2:i]); flag != nil && flag.Arg != nil {
+				return f.completeFlagArg(flag, word[i+1:], word[:i+1])
+			}
+			return nil, completeDefault
+		}
+	}
+	if !operands && strings.HasPrefix(word, "-") {
+		var candidates []string
+		for _, flag := range f.flags {
+			if flag.Hidden {
+				continue
+			}
+			if flag.Long != "" && strings.HasPrefix("--"+flag.Long, word) {
+				candidates = append(candidates, "--"+flag.Long)
+			}
+			if flag.Short != "" && strings.HasPrefix("-"+flag.Short, word) {
+				candidates = append(candidates, "-"+flag.Short)
+			}
+		}
+		return candidates, completeDefault
+	}
+
+	if f.command != nil && len(f.command.commands) > 0 {
+		var candidates []string
+		for _, cmd := range f.command.commands {
+			if strings.HasPrefix(cmd.Name, word) {
+				candidates = append(candidates, cmd.Name)
+			}
+		}
+		return candidates, completeDefault
+	}
+	if f.CompleteArgs != nil {
+		return filterCandidates(f.CompleteArgs(word, f), word, ""), completeDefault
+	}
+	return nil, completeFiles
+}
+
+// completeFlagArg returns the completion candidates for an option argument.
+// Candidates are prefixed with prefix (eg. "--format=").
+func (f *FlagSet) completeFlagArg(flag *Flag, word, prefix string) ([]string, int) {
+	if flag.Complete != nil {
+		return filterCandidates(flag.Complete(word, f), word, prefix), completeDefault
+	}
+	if e, ok := flag.Value.(*enumValue); ok {
+		return filterCandidates(e.choices, word, prefix), completeDefault
+	}
+	switch flag.Arg.Name {
+	case "file", "path", "dir":
+		return nil, completeFiles
+	}
+	return nil, completeDefault
+}
+
+// filterCandidates returns the candidates starting with word, prefixed with prefix.
+func filterCandidates(candidates []string, word, prefix string) []string {
+	var filtered []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			filtered = append(filtered, prefix+candidate)
+		}
+	}
+	return filtered
+}
+
+// program returns the program name, as used in help usage and completion scripts.
+func (f *FlagSet) program() string {
+	if f.command != nil {
+		root := f.command
+		for root.parent != nil {
+			root = root.parent
+		}
+		return root.Path()
+	}
+	return filepath.Base(os.Args[0])
+}
+
+var completionScripts = map[string]*template.Template{
+	"bash": template.Must(template.New("bash").Parse(`# bash completion for {{.Name}}
+
+_{{.Function}}_complete() {
+    local line="${COMP_LINE:0:COMP_POINT}" words out cur directive
+    read -ra words <<< "$line"
+    if [[ -z $line || $line == *[[:space:]] ]]; then
+        words+=("")
+    fi
+    cur="${words[${#words[@]}-1]}"
+
+    local IFS=$'\n'
+    out=($("${words[0]}" __complete "${words[@]:1}" 2>/dev/null))
+    directive=0
+    if [[ ${#out[@]} -gt 0 && ${out[${#out[@]}-1]} == :* ]]; then
+        directive="${out[${#out[@]}-1]#:}"
+        unset 'out[${#out[@]}-1]'
+    fi
+
+    if [[ $cur == *=* && $COMP_WORDBREAKS == *=* ]]; then
+        out=("${out[@]#"${cur%%=*}="}")
+    fi
+    COMPREPLY=("${out[@]}")
+    if (( directive & 1 )); then
+        compopt -o default
+    fi
+}
+
+complete -F _{{.Function}}_complete {{.Name}}
+`)),
+
+	"zsh": template.Must(template.New("zsh").Parse(`#compdef {{.Name}}
+
+# zsh completion for {{.Name}}
+
+_{{.Function}}() {
+    local -a out
+    local directive=0
+    out=("${(@f)$(${words[1]} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ ${out[-1]} == :* ]]; then
+        directive=${out[-1]#:}
+        out=("${(@)out[1,-2]}")
+    fi
+    out=(${out:#})
+
+    if (( directive & 1 )) && (( ${#out} == 0 )); then
+        _files
+        return
+    fi
+    compadd -- "${out[@]}"
+}
+
+compdef _{{.Function}} {{.Name}}
+`)),
+
+	"fish": template.Must(template.New("fish").Parse(`# fish completion for {{.Name}}
+
+function __{{.Function}}_complete
+    set -l cur (commandline -ct)
+    set -l words (commandline -opc) "$cur"
+    set -l out ($words[1] __complete $words[2..-1] 2>/dev/null)
+    set -l directive 0
+    if test (count $out) -gt 0; and string match -qr '^:' -- $out[-1]
+        set directive (string sub -s 2 -- $out[-1])
+        set -e out[-1]
+    end
+
+    if test (math "$directive % 2") -eq 1; and test (count $out) -eq 0
+        __fish_complete_path $cur
+        return
+    end
+    printf '%s\n' $out
+end
+
+complete -c {{.Name}} -f -a '(__{{.Function}}_complete)'
+`)),
+
+	"powershell": template.Must(template.New("powershell").Parse(`# powershell completion for {{.Name}}
+
+Register-ArgumentCompleter -Native -CommandName '{{.Name}}' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $words = @($commandAst.CommandElements |
+        Where-Object { $_.Extent.StartOffset -lt $cursorPosition } |
+        ForEach-Object { $_.Extent.Text })
+    if ($wordToComplete -eq '') {
+        $words += ''
+    }
+    $program, $arguments = $words
+
+    $out = @(& $program __complete @arguments 2>$null)
+    $directive = 0
+    if ($out.Count -gt 0 -and $out[-1] -like ':*') {
+        $directive = [int]$out[-1].Substring(1)
+        $out = @($out | Select-Object -SkipLast 1)
+    }
+
+    if (($directive -band 1) -and $out.Count -eq 0) {
+        return
+    }
+    $out | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`)),
+}
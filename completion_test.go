@@ -0,0 +1,194 @@
+package flaq
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestCompletion(t *testing.T) {
+	extensions := map[string]string{
+		"bash":       "bash",
+		"zsh":        "zsh",
+		"fish":       "fish",
+		"powershell": "ps1",
+	}
+
+	for shell, ext := range extensions {
+		t.Run(shell, func(t *testing.T) {
+			cmd := &Command{Name: "greet-cli"}
+
+			var buf bytes.Buffer
+			require.NoError(t, cmd.Completion(&buf, shell))
+
+			golden := "testdata/completion." + ext
+			if *update {
+				require.NoError(t, ioutil.WriteFile(golden, buf.Bytes(), 0644))
+			}
+			expected, err := ioutil.ReadFile(golden)
+			require.NoError(t, err)
+			require.Equal(t, string(expected), buf.String())
+		})
+	}
+}
+
+func TestCompletionUnsupportedShell(t *testing.T) {
+	flags := &FlagSet{}
+	require.EqualError(t, flags.Completion(ioutil.Discard, "tcsh"), "unsupported shell tcsh")
+}
+
+func TestComplete(t *testing.T) {
+	fixtures := []struct {
+		words      []string
+		candidates []string
+		directive  int
+	}{
+		{
+			words:      []string{"--"},
+			candidates: []string{"--name", "--format", "--yell", "--config"},
+		},
+		{
+			words:      []string{"-"},
+			candidates: []string{"--name", "-n", "--format", "-f", "--yell", "-y", "--config", "-c"},
+		},
+		{
+			words:      []string{"--fo"},
+			candidates: []string{"--format"},
+		},
+		{
+			words:      []string{"--format", ""},
+			candidates: []string{"json", "yaml"},
+		},
+		{
+			words:      []string{"-f", "y"},
+			candidates: []string{"yaml"},
+		},
+		{
+			words:      []string{"--format=j"},
+			candidates: []string{"--format=json"},
+		},
+		{
+			words:      []string{"-nx", "-yf", ""},
+			candidates: []string{"json", "yaml"},
+		},
+		{
+			words:     []string{"--name", "x", ""},
+			directive: completeFiles,
+		},
+		{
+			words: []string{"--name", ""},
+		},
+		{
+			words:     []string{"--config", ""},
+			directive: completeFiles,
+		},
+		{
+			words:     []string{"op", ""},
+			directive: completeFiles,
+		},
+		{
+			words:     []string{"--", "--"},
+			directive: completeFiles,
+		},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%q", fixture.words), func(t *testing.T) {
+			var name, format string
+			var yell bool
+
+			flags := &FlagSet{}
+			flags.String(&name, "name", "n", "")
+			flags.Enum(&format, "format", "f", "", "json", "yaml")
+			flags.Bool(&yell, "yell", "y", "", false)
+			flags.Config("config", "c", "")
+			flags.Add(&Flag{Long: "secret", Value: new(boolValue), Hidden: true})
+
+			candidates, directive := flags.complete(fixture.words)
+			require.Equal(t, fixture.candidates, candidates)
+			require.Equal(t, fixture.directive, directive)
+		})
+	}
+}
+
+func TestCompleteCommands(t *testing.T) {
+	var verbose bool
+	var format string
+
+	root := &Command{Name: "tool"}
+	root.Flags.Add(&Flag{Long: "verbose", Value: (*boolValue)(&verbose), Persistent: true})
+	remote := &Command{Name: "remote"}
+	remote.Flags.Enum(&format, "format", "", "", "json", "yaml")
+	root.Add(remote)
+	root.Add(&Command{Name: "status"})
+
+	candidates, _ := root.Flags.complete([]string{"--verbose", ""})
+	require.Equal(t, []string{"remote", "status"}, candidates)
+
+	candidates, _ = root.Flags.complete([]string{"remote", "--"})
+	require.Equal(t, []string{"--format", "--verbose"}, candidates)
+
+	candidates, _ = root.Flags.complete([]string{"remote", "--format", "j"})
+	require.Equal(t, []string{"json"}, candidates)
+}
+
+func TestCompleteFuncs(t *testing.T) {
+	var context, cluster string
+
+	flags := &FlagSet{}
+	flags.String(&context, "context", "", "")
+	flags.Add(&Flag{
+		Long:  "cluster",
+		Value: (*stringValue)(&cluster),
+		Arg:   &FlagArg{},
+		Complete: func(word string, flags *FlagSet) []string {
+			if context == "prod" {
+				return []string{"prod-eu", "prod-us", "staging"}
+			}
+			return []string{"dev"}
+		},
+	})
+	flags.CompleteArgs = func(word string, flags *FlagSet) []string {
+		return append([]string{"pod-a", "pod-b"}, flags.Args()...)
+	}
+
+	candidates, directive := flags.complete([]string{"--context=prod", "--cluster", "prod-"})
+	require.Equal(t, []string{"prod-eu", "prod-us"}, candidates)
+	require.Equal(t, completeDefault, directive)
+
+	context = ""
+	candidates, _ = flags.complete([]string{"--cluster", ""})
+	require.Equal(t, []string{"dev"}, candidates)
+
+	candidates, _ = flags.complete([]string{"--context=prod", "--cluster=s"})
+	require.Equal(t, []string{"--cluster=staging"}, candidates)
+
+	candidates, directive = flags.complete([]string{"pod-c", "pod"})
+	require.Equal(t, []string{"pod-a", "pod-b", "pod-c"}, candidates)
+	require.Equal(t, completeDefault, directive)
+}
+
+func TestParseComplete(t *testing.T) {
+	var out bytes.Buffer
+	var name string
+	var code = -1
+
+	flags := &FlagSet{Output: &out, Exit: func(c int) { code = c }}
+	flags.String(&name, "name", "n", "")
+
+	require.Equal(t, ErrComplete, flags.Parse([]string{"__complete", "--na"}))
+	require.Equal(t, -1, code)
+	require.Equal(t, "--name\n:0\n", out.String())
+
+	out.Reset()
+	flags.ErrorHandling = ExitOnError
+	require.Equal(t, ErrComplete, flags.Parse([]string{"__complete", "--na"}))
+	require.Equal(t, 0, code)
+	require.Equal(t, "--name\n:0\n", out.String())
+}
@@ -0,0 +1,227 @@
+package flaq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// configFormats holds the configuration file decoders registered with
+// RegisterConfigFormat, by file extension.
+var configFormats = map[string]func(data []byte, v interface{}) error{}
+
+// RegisterConfigFormat registers a decoder for configuration files with the
+// given extension (eg. ".toml"). decode has the signature of the Unmarshal
+// functions of common encoding packages, and is given a pointer to a
+// map[string]interface{} to decode the file into. JSON and INI files are
+// supported without registration.
+func RegisterConfigFormat(ext string, decode func(data []byte, v interface{}) error) {
+	configFormats[strings.ToLower(ext)] = decode
+}
+
+// Config adds a flag with specified long/short form and description, whose
+// argument is the path of a configuration file to load option values from.
+func Config(long, short, description string) {
+	flags.Config(long, short, description)
+}
+
+// Config adds a flag with specified long/short form and description, whose
+// argument is the path of a configuration file to load option values from.
+func (f *FlagSet) Config(long, short, description string) {
+	f.Add(&Flag{
+		Long:        long,
+		Short:       short,
+		Description: description,
+		Value:       (*stringValue)(&f.ConfigFile),
+		Arg:         &FlagArg{Name: "file"},
+	})
+}
+
+// parseConfig sets flag values from the configuration file, if any.
+// Keys are matched against long option names, keys in nested sections being
+// joined with a dash (eg. port in a [server] section maps to --server-port).
+// Options already given on the command line or to an ancestor command are
+// left untouched. With subcommands, each command sets its own options, and
+// unknown keys are reported by the selected command only.
+func (f *FlagSet) parseConfig() error {
+	path := f.configFile()
+	if path == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	values, err := decodeConfig(filepath.Ext(path), data)
+	if err != nil {
+		return fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		flag := f.lookupLong(key)
+		if flag == nil {
+			if f.handsOff() || f.ancestorDefines(key) {
+				continue
+			}
+			return &UnknownFlagError{Name: "--" + key, ConfigFile: path, Suggestions: f.suggest(key)}
+		}
+		if f.actual[flag] > 0 || f.givenToAncestor(flag) {
+			continue
+		}
+		resetValue(flag.Value)
+		for _, val := range values[key] {
+			if err := setConfigValue(flag.Value, val); err != nil {
+				return &InvalidValueError{Flag: flag, Name: "--" + key, Value: val, ConfigFile: path, Err: err}
+			}
+		}
+		f.provide(flag)
+	}
+	return nil
+}
+
+// setConfigValue sets a flag value from a configuration file. The value of
+// a count flag is either the count itself, or a boolean counting as one.
+func setConfigValue(value Value, val string) error {
+	count, ok := value.(*countValue)
+	if !ok {
+		return value.Set(val)
+	}
+	n, err := strconv.Atoi(val)
+	if b, berr := strconv.ParseBool(val); err != nil && berr == nil {
+		n, err = 0, nil
+		if b {
+			n = 1
+		}
+	}
+	if err != nil || n < 0 {
+		return errors.New("expected a count")
+	}
+	*count = countValue(n)
+	return nil
+}
+
+// configFile returns the path of the configuration file to load: the
+// ConfigFile of the flagset, or else the one of the closest ancestor command.
+func (f *FlagSet) configFile() string {
+	if parent := f.parent(); f.ConfigFile == "" && parent != nil {
+		return parent.configFile()
+	}
+	return f.ConfigFile
+}
+
+// lookupLong returns the flag with the given long name, or nil.
+func (f *FlagSet) lookupLong(name string) *Flag {
+	if flag := f.Lookup(name); flag != nil && flag.Long == name {
+		return flag
+	}
+	return nil
+}
+
+// ancestorDefines reports whether an ancestor command defines the flag
+// with the given long name.
+func (f *FlagSet) ancestorDefines(name string) bool {
+	for parent := f.parent(); parent != nil; parent = parent.parent() {
+		if parent.lookupLong(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeConfig decodes configuration file data according to the file
+// extension, and returns the values found for each key.
+func decodeConfig(ext string, data []byte) (map[string][]string, error) {
+	var doc map[string]interface{}
+	var err error
+
+	switch ext = strings.ToLower(ext); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&doc)
+	case ".ini":
+		return decodeINI(data)
+	default:
+		decode, ok := configFormats[ext]
+		if !ok {
+			return nil, fmt.Errorf("unsupported format %q", ext)
+		}
+		err = decode(data, &doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]string)
+	flattenConfig("", doc, values)
+	return values, nil
+}
+
+// flattenConfig collects the values of a decoded configuration document.
+// Arrays are expanded to one value per element.
+func flattenConfig(key string, doc interface{}, values map[string][]string) {
+	switch doc := doc.(type) {
+	case map[string]interface{}:
+		for k, v := range doc {
+			if key != "" {
+				k = key + "-" + k
+			}
+			flattenConfig(k, v, values)
+		}
+	case []interface{}:
+		for _, v := range doc {
+			values[key] = append(values[key], fmt.Sprint(v))
+		}
+	case nil:
+	default:
+		values[key] = append(values[key], fmt.Sprint(doc))
+	}
+}
+
+// decodeINI decodes INI data. Keys in a [section] are prefixed with the
+// section name, and repeated keys give multiple values.
+func decodeINI(data []byte) (map[string][]string, error) {
+	values := make(map[string][]string)
+	section := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "", line[0] == ';', line[0] == '#':
+			continue
+		case line[0] == '[':
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("line %d: invalid section %s", n, line)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		key, val := line, ""
+		if i := strings.IndexByte(line, '='); i >= 0 {
+			key, val = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		}
+		if len(val) > 1 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+			val = val[1 : len(val)-1]
+		}
+		if section != "" {
+			key = section + "-" + key
+		}
+		values[key] = append(values[key], val)
+	}
+	return values, scanner.Err()
+}
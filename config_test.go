@@ -0,0 +1,197 @@
+package flaq
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseConfig(t *testing.T) {
+	RegisterConfigFormat(".toml", toml.Unmarshal)
+	RegisterConfigFormat(".yaml", yaml.Unmarshal)
+
+	for _, format := range []string{"json", "toml", "yaml", "ini"} {
+		t.Run(format, func(t *testing.T) {
+			var name string
+			var port int
+			var yell bool
+			var timeout time.Duration
+
+			flags := &FlagSet{}
+			flags.String(&name, "name", "n", "")
+			flags.Int(&port, "port", "p", "")
+			flags.Bool(&yell, "yell", "", "", false)
+			flags.Duration(&timeout, "server-timeout", "", "")
+			flags.Config("config", "c", "")
+
+			err := flags.Parse([]string{"--config", "testdata/config." + format})
+			require.NoError(t, err)
+			require.Equal(t, format, name)
+			require.Equal(t, 8080, port)
+			require.True(t, yell)
+			require.Equal(t, 5*time.Second, timeout)
+		})
+	}
+}
+
+func TestParseConfigPrecedence(t *testing.T) {
+	os.Setenv("PORT", "9090")
+	defer os.Unsetenv("PORT")
+
+	var name string
+	var port int
+
+	flags := &FlagSet{ConfigFile: "testdata/config.json"}
+	flags.String(&name, "name", "n", "")
+	flags.Add(&Flag{Long: "port", Value: (*intValue)(&port), Arg: &FlagArg{}, Env: "PORT"})
+	flags.Add(&Flag{Long: "yell", Value: new(boolValue)})
+	flags.Add(&Flag{Long: "server-timeout", Value: new(durationValue), Arg: &FlagArg{}})
+
+	err := flags.Parse([]string{"--name=cli"})
+	require.NoError(t, err)
+	require.Equal(t, "cli", name)
+	require.Equal(t, 9090, port)
+}
+
+func TestParseConfigSlicePrecedence(t *testing.T) {
+	fixtures := []struct {
+		args []string
+		env  string
+		tags []string
+	}{
+		{
+			tags: []string{"a", "b"},
+		},
+		{
+			env:  "env",
+			tags: []string{"env"},
+		},
+		{
+			args: []string{"--tag", "cli"},
+			env:  "env",
+			tags: []string{"cli"},
+		},
+	}
+
+	defer os.Unsetenv("TAG")
+	for _, fixture := range fixtures {
+		os.Setenv("TAG", fixture.env)
+
+		var tags []string
+		flags := &FlagSet{ConfigFile: "testdata/tags.json"}
+		flags.Add(&Flag{Long: "tag", Value: &stringSliceValue{value: &tags}, Arg: &FlagArg{}, Env: "TAG"})
+
+		require.NoError(t, flags.Parse(fixture.args))
+		require.Equal(t, fixture.tags, tags)
+	}
+}
+
+func TestParseConfigInvalidValue(t *testing.T) {
+	var yell int
+
+	flags := &FlagSet{ConfigFile: "testdata/config.json"}
+	flags.Add(&Flag{Long: "yell", Value: (*intValue)(&yell), Arg: &FlagArg{}})
+	flags.Add(&Flag{Long: "name", Value: new(stringValue), Arg: &FlagArg{}})
+	flags.Add(&Flag{Long: "port", Value: new(intValue), Arg: &FlagArg{}})
+	flags.Add(&Flag{Long: "server-timeout", Value: new(durationValue), Arg: &FlagArg{}})
+
+	err := flags.Parse([]string{})
+	require.EqualError(t, err, `invalid value "true" for --yell in config file testdata/config.json: expected int`)
+
+	var invalid *InvalidValueError
+	require.True(t, errors.As(err, &invalid))
+	require.Equal(t, "testdata/config.json", invalid.ConfigFile)
+	require.Error(t, errors.Unwrap(err))
+}
+
+func TestParseConfigCommand(t *testing.T) {
+	for _, args := range [][]string{
+		{"sub", "--config", "testdata/command.json"},
+		{"--config", "testdata/command.json", "sub"},
+	} {
+		var name, token string
+
+		root := &Command{Name: "tool"}
+		root.Flags.Config("config", "c", "")
+		root.Flags.Lookup("config").Persistent = true
+		root.Flags.Add(&Flag{Long: "token", Value: (*stringValue)(&token), Arg: &FlagArg{}, Persistent: true})
+		sub := &Command{Name: "sub"}
+		sub.Flags.String(&name, "name", "n", "")
+		root.Add(sub)
+
+		cmd, err := root.Parse(args)
+		require.NoError(t, err, args)
+		require.Equal(t, sub, cmd)
+		require.Equal(t, "x", name)
+		require.Equal(t, "t", token)
+	}
+
+	root := &Command{Name: "tool"}
+	root.Flags.Config("config", "c", "")
+	root.Flags.Add(&Flag{Long: "token", Value: new(stringValue), Arg: &FlagArg{}})
+	root.Add(&Command{Name: "sub"})
+
+	_, err := root.Parse([]string{"--config", "testdata/command.json"})
+	require.EqualError(t, err, "unknown option --name in config file testdata/command.json")
+}
+
+func TestParseConfigCount(t *testing.T) {
+	var verbose, quiet int
+
+	flags := &FlagSet{ConfigFile: "testdata/count.json"}
+	flags.Count(&verbose, "verbose", "v", "")
+	flags.Count(&quiet, "quiet", "q", "")
+
+	require.NoError(t, flags.Parse([]string{}))
+	require.Equal(t, 3, verbose)
+	require.Equal(t, 1, quiet)
+
+	require.EqualError(t, setConfigValue((*countValue)(&verbose), "abc"), "expected a count")
+	require.EqualError(t, setConfigValue((*countValue)(&verbose), "-1"), "expected a count")
+	require.NoError(t, setConfigValue((*countValue)(&verbose), "false"))
+	require.Equal(t, 0, verbose)
+}
+
+func TestParseConfigUnknownOption(t *testing.T) {
+	var name string
+
+	flags := &FlagSet{ConfigFile: "testdata/unknown.json"}
+	flags.String(&name, "name", "n", "")
+
+	err := flags.Parse([]string{})
+	require.EqualError(t, err, "unknown option --nme in config file testdata/unknown.json, did you mean --name?")
+}
+
+func TestParseConfigUnsupportedFormat(t *testing.T) {
+	flags := &FlagSet{ConfigFile: "testdata/config.xml"}
+
+	err := flags.Parse([]string{})
+	require.Error(t, err)
+
+	_, err = decodeConfig(".hcl", []byte{})
+	require.EqualError(t, err, `unsupported format ".hcl"`)
+}
+
+func TestDecodeINI(t *testing.T) {
+	values, err := decodeINI([]byte(`
+# comment
+include = a
+include = 'b'
+
+[log]
+level = debug
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"include":   {"a", "b"},
+		"log-level": {"debug"},
+	}, values)
+
+	_, err = decodeINI([]byte("[log"))
+	require.EqualError(t, err, "line 1: invalid section [log")
+}
@@ -0,0 +1,130 @@
+package flaq
+
+import (
+	"fmt"
+	"strings"
+)
+
+type constraintKind int
+
+const (
+	exclusive constraintKind = iota // At most one of the flags can be given.
+	requires                        // The first flag requires all the others.
+	oneOf                           // At least one of the flags must be given.
+)
+
+// constraint is a relationship between flags, checked once parsing is done.
+type constraint struct {
+	kind  constraintKind
+	names []string
+}
+
+// MutuallyExclusive declares that at most one of the named flags can be given.
+func MutuallyExclusive(names ...string) {
+	flags.MutuallyExclusive(names...)
+}
+
+// Requires declares that the named flag, when given, requires the other ones.
+func Requires(name string, required ...string) {
+	flags.Requires(name, required...)
+}
+
+// OneOf declares that at least one of the named flags must be given.
+func OneOf(names ...string) {
+	flags.OneOf(names...)
+}
+
+// MutuallyExclusive declares that at most one of the named flags can be given.
+// Flags are named by their long or short form, without dashes.
+func (f *FlagSet) MutuallyExclusive(names ...string) {
+	f.constraints = append(f.constraints, constraint{exclusive, names})
+}
+
+// Requires declares that the named flag, when given, requires the other ones.
+// Flags are named by their long or short form, without dashes.
+func (f *FlagSet) Requires(name string, required ...string) {
+	f.constraints = append(f.constraints, constraint{requires, append([]string{name}, required...)})
+}
+
+// OneOf declares that at least one of the named flags must be given.
+// Flags are named by their long or short form, without dashes.
+func (f *FlagSet) OneOf(names ...string) {
+	f.constraints = append(f.constraints, constraint{oneOf, names})
+}
+
+// checkConstraints returns an error naming the flags that break a constraint.
+// A flag is considered given when set on the command line, through an
+// environment variable or in a configuration file.
+func (f *FlagSet) checkConstraints() error {
+	for _, c := range f.constraints {
+		names := c.flagNames(f)
+		var given, missing []string
+		for i, name := range c.names {
+			if flag := f.Lookup(name); flag != nil && f.given(flag) {
+				given = append(given, names[i])
+			} else {
+				missing = append(missing, names[i])
+			}
+		}
+
+		switch {
+		case c.kind == exclusive && len(given) > 1:
+			return fmt.Errorf("options %s are mutually exclusive", joinNames(given, "and"))
+		case c.kind == requires && len(given) > 0 && given[0] == names[0] && len(missing) > 0:
+			return fmt.Errorf("option %s requires %s", names[0], joinNames(missing, "and"))
+		case c.kind == oneOf && len(given) == 0:
+			return fmt.Errorf("one of the options %s is required", joinNames(missing, "or"))
+		}
+	}
+	return nil
+}
+
+// checkConstraintNames returns an error if a constraint names a flag that
+// is not defined.
+func (f *FlagSet) checkConstraintNames() error {
+	for _, c := range f.constraints {
+		for _, name := range c.names {
+			if f.Lookup(name) == nil {
+				return fmt.Errorf(`unknown option "%s" in flag constraint`, name)
+			}
+		}
+	}
+	return nil
+}
+
+// flagNames returns the names of the constrained flags as they appear on the
+// command line. Flags that are not defined, as reported by Validate, are
+// named as given, long names being assumed for names of several characters.
+func (c constraint) flagNames(f *FlagSet) []string {
+	names := make([]string, len(c.names))
+	for i, name := range c.names {
+		if flag := f.Lookup(name); flag != nil {
+			names[i] = flagName(flag.Long, flag.Short)
+		} else if len(name) == 1 {
+			names[i] = "-" + name
+		} else {
+			names[i] = "--" + name
+		}
+	}
+	return names
+}
+
+// usage describes the constraint for the help usage.
+func (c constraint) usage(f *FlagSet) string {
+	names := c.flagNames(f)
+	switch c.kind {
+	case exclusive:
+		return joinNames(names, "and") + " are mutually exclusive"
+	case requires:
+		return names[0] + " requires " + joinNames(names[1:], "and")
+	}
+	return "one of " + joinNames(names, "or") + " is required"
+}
+
+// joinNames joins flag names, using a conjunction for the last one (eg. "--a, --b and --c").
+func joinNames(names []string, conjunction string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " " + conjunction + " " + names[len(names)-1]
+}
@@ -0,0 +1,100 @@
+package flaq
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConstraints(t *testing.T) {
+	fixtures := []struct {
+		args []string
+		err  string
+	}{
+		{
+			args: []string{"--json"},
+		},
+		{
+			args: []string{"--json", "-y"},
+			err:  "options --json and --yaml are mutually exclusive",
+		},
+		{
+			args: []string{"--json", "--tls-key=k"},
+			err:  "option --tls-key requires --tls-cert",
+		},
+		{
+			args: []string{"--json", "--tls-key=k", "--tls-cert=c"},
+		},
+		{
+			args: []string{"--tls-cert=c"},
+			err:  "one of the options --json or --yaml is required",
+		},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%q", fixture.args), func(t *testing.T) {
+			var json, yaml bool
+			var key, cert string
+
+			flags := &FlagSet{}
+			flags.Bool(&json, "json", "", "", false)
+			flags.Bool(&yaml, "yaml", "y", "", false)
+			flags.String(&key, "tls-key", "", "")
+			flags.String(&cert, "tls-cert", "", "")
+			flags.MutuallyExclusive("json", "y")
+			flags.Requires("tls-key", "tls-cert")
+			flags.OneOf("json", "yaml")
+
+			err := flags.Parse(fixture.args)
+			if fixture.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, fixture.err)
+			}
+		})
+	}
+}
+
+func TestValidateConstraintUnknownOption(t *testing.T) {
+	var json bool
+
+	flags := &FlagSet{}
+	flags.Bool(&json, "json", "", "", false)
+	flags.MutuallyExclusive("json", "yaml")
+
+	require.EqualError(t, flags.Validate(), `unknown option "yaml" in flag constraint`)
+	require.NotPanics(t, func() { flags.Usage() })
+	require.NoError(t, flags.Parse([]string{"--json"}))
+}
+
+func TestUsageConstraints(t *testing.T) {
+	var json, yaml, toml bool
+	var key, cert string
+
+	flags := &FlagSet{}
+	flags.Bool(&json, "json", "", "JSON output", false)
+	flags.Bool(&yaml, "yaml", "", "YAML output", false)
+	flags.Bool(&toml, "toml", "", "TOML output", false)
+	flags.String(&key, "tls-key", "", "TLS key")
+	flags.String(&cert, "tls-cert", "", "TLS certificate")
+	flags.MutuallyExclusive("json", "yaml", "toml")
+	flags.Requires("tls-key", "tls-cert")
+	flags.OneOf("json", "yaml")
+
+	expectedUsage := `Usage: flaq.test [options]
+
+Options
+      --json                JSON output
+      --tls-cert <string>   TLS certificate
+      --tls-key <string>    TLS key
+      --toml                TOML output
+      --yaml                YAML output
+
+Constraints
+  --json, --yaml and --toml are mutually exclusive
+  --tls-key requires --tls-cert
+  one of --json or --yaml is required
+`
+	require.Equal(t, expectedUsage, flags.Usage())
+}
@@ -0,0 +1,35 @@
+package flaq
+
+import "os"
+
+// envName returns the name of the environment variable bound to a flag,
+// including the FlagSet prefix. It returns an empty string if there is none.
+func (f *FlagSet) envName(flag *Flag) string {
+	if flag.Env == "" {
+		return ""
+	}
+	return f.EnvPrefix + flag.Env
+}
+
+// parseEnv sets flag values from their environment variables. Empty variables
+// are ignored, as well as options already given on the command line or to an
+// ancestor command. Environment variables take precedence over configuration
+// files, and replace the values of slice and map flags rather than appending.
+func (f *FlagSet) parseEnv() error {
+	for _, flag := range f.flags {
+		name := f.envName(flag)
+		if name == "" || f.actual[flag] > 0 || f.givenToAncestor(flag) {
+			continue
+		}
+		val, ok := os.LookupEnv(name)
+		if !ok || val == "" {
+			continue
+		}
+		resetValue(flag.Value)
+		if err := flag.Value.Set(val); err != nil {
+			return &InvalidValueError{Flag: flag, Name: flagName(flag.Long, flag.Short), Value: val, Env: name, Err: err}
+		}
+		f.provide(flag)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package flaq
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnv(t *testing.T) {
+	fixtures := []struct {
+		args []string
+		env  string
+		port int
+	}{
+		{
+			port: 8080,
+		},
+		{
+			env:  "9090",
+			port: 9090,
+		},
+		{
+			args: []string{"--port=7070"},
+			env:  "9090",
+			port: 7070,
+		},
+	}
+
+	defer os.Unsetenv("APP_PORT")
+	for _, fixture := range fixtures {
+		os.Setenv("APP_PORT", fixture.env)
+
+		port := 8080
+		flags := &FlagSet{EnvPrefix: "APP_"}
+		flags.Add(&Flag{
+			Long:  "port",
+			Value: (*intValue)(&port),
+			Arg:   &FlagArg{Name: "int"},
+			Env:   "PORT",
+		})
+
+		require.NoError(t, flags.Parse(fixture.args))
+		require.Equal(t, fixture.port, port)
+	}
+}
+
+func TestParseEnvInvalidValue(t *testing.T) {
+	os.Setenv("PORT", "abc")
+	defer os.Unsetenv("PORT")
+
+	var port int
+	flags := &FlagSet{}
+	flags.Add(&Flag{Long: "port", Value: (*intValue)(&port), Env: "PORT"})
+
+	err := flags.Parse([]string{})
+	require.EqualError(t, err, `invalid value "abc" for --port from environment variable PORT: expected int`)
+
+	var invalid *InvalidValueError
+	require.True(t, errors.As(err, &invalid))
+	require.Equal(t, "PORT", invalid.Env)
+	require.Error(t, errors.Unwrap(err))
+}
+
+func TestParseStructEnv(t *testing.T) {
+	os.Setenv("NAME", "env")
+	os.Setenv("YELL", "true")
+	defer os.Unsetenv("NAME")
+	defer os.Unsetenv("YELL")
+
+	var opts = struct {
+		Name string `flaq:"-n, --name string env:NAME  name of the person to greet"`
+		Yell bool   `flaq:"    --yell env:YELL         whether to yell or not"`
+	}{}
+
+	flags := &FlagSet{}
+	flags.Struct(&opts)
+
+	require.NoError(t, flags.Parse([]string{}))
+	require.Equal(t, "env", opts.Name)
+	require.True(t, opts.Yell)
+}
+
+func TestParseEnvRequired(t *testing.T) {
+	os.Setenv("NAME", "env")
+	defer os.Unsetenv("NAME")
+
+	var name string
+	flags := &FlagSet{}
+	flags.Add(&Flag{Long: "name", Value: (*stringValue)(&name), Arg: &FlagArg{}, Env: "NAME", Required: true})
+
+	require.NoError(t, flags.Parse([]string{}))
+	require.Equal(t, "env", name)
+}
+
+func TestParseEnvPersistentSlice(t *testing.T) {
+	os.Setenv("TAGS", "env")
+	defer os.Unsetenv("TAGS")
+
+	fixtures := []struct {
+		args []string
+		tags []string
+	}{
+		{
+			args: []string{"sub"},
+			tags: []string{"env"},
+		},
+		{
+			args: []string{"sub", "-t", "x"},
+			tags: []string{"x"},
+		},
+		{
+			args: []string{"-t", "x", "sub", "-t", "y"},
+			tags: []string{"x", "y"},
+		},
+	}
+
+	for _, fixture := range fixtures {
+		var tags []string
+		root := &Command{Name: "tool"}
+		root.Flags.Add(&Flag{Long: "tag", Short: "t", Value: &stringSliceValue{value: &tags}, Arg: &FlagArg{}, Env: "TAGS", Persistent: true})
+		root.Add(&Command{Name: "sub"})
+
+		_, err := root.Parse(fixture.args)
+		require.NoError(t, err)
+		require.Equal(t, fixture.tags, tags)
+	}
+}
+
+func TestParseEnvAfterParse(t *testing.T) {
+	os.Setenv("INCLUDE", "a,b")
+	defer os.Unsetenv("INCLUDE")
+
+	var includes []string
+	flags := &FlagSet{}
+	flags.StringSlice(&includes, "include", "i", "", true)
+	flags.Lookup("include").Env = "INCLUDE"
+
+	require.NoError(t, flags.Parse([]string{"--include", "x"}))
+	require.Equal(t, []string{"x"}, includes)
+
+	require.NoError(t, flags.Parse(nil))
+	require.Equal(t, []string{"a", "b"}, includes)
+}
+
+func TestUsageEnv(t *testing.T) {
+	var port int
+
+	flags := &FlagSet{EnvPrefix: "APP_"}
+	flags.Add(&Flag{
+		Long:        "port",
+		Description: "listen port",
+		Value:       (*intValue)(&port),
+		Arg:         &FlagArg{Name: "int"},
+		Env:         "PORT",
+		Required:    true,
+	})
+
+	expectedUsage := `Usage: flaq.test [options]
+
+Options
+      --port <int>   listen port [$APP_PORT] (required)
+`
+	require.Equal(t, expectedUsage, flags.Usage())
+}
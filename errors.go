@@ -0,0 +1,154 @@
+package flaq
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrHelp is returned by Parse with ContinueOnError error handling when the
+// help flag is seen, once help usage is written.
+var ErrHelp = errors.New("flaq: help requested")
+
+// ErrVersion is returned by Parse with ContinueOnError error handling when the
+// version flag is seen, once the version text is written.
+var ErrVersion = errors.New("flaq: version requested")
+
+// ErrComplete is returned by Parse with ContinueOnError error handling when
+// called back by a completion script, once completion candidates are written.
+var ErrComplete = errors.New("flaq: completion requested")
+
+// UnknownFlagError is returned when an option is not defined.
+// Unknown keys of a configuration file are reported with the file, rather than
+// an argument and its position.
+type UnknownFlagError struct {
+	Name        string   // Option name as given, eg. "--verbos" or "-x".
+	Arg         string   // Raw argument, eg. "--verbos=true".
+	Pos         int      // Position of the argument.
+	ConfigFile  string   // Configuration file the option was found in, if any.
+	Suggestions []string // Names of the closest defined options, for unknown long options only.
+}
+
+func (e *UnknownFlagError) Error() string {
+	name := e.Name
+	if e.ConfigFile != "" {
+		name += " in config file " + e.ConfigFile
+	}
+	if len(e.Suggestions) > 0 {
+		return fmt.Sprintf("unknown option %s, did you mean %s?", name, joinNames(e.Suggestions, "or"))
+	}
+	return "unknown option " + name
+}
+
+// UnknownCommandError is returned by Command.Parse when an operand does not
+// name a subcommand.
+type UnknownCommandError struct {
+	Name        string   // Command name as given.
+	Pos         int      // Position of the argument.
+	Suggestions []string // Names of the closest subcommands, if any.
+}
+
+func (e *UnknownCommandError) Error() string {
+	if len(e.Suggestions) > 0 {
+		return fmt.Sprintf("unknown command %s, did you mean %s?", e.Name, joinNames(e.Suggestions, "or"))
+	}
+	return "unknown command " + e.Name
+}
+
+// AmbiguousFlagError is returned when an option abbreviation matches several options.
+type AmbiguousFlagError struct {
+	Name       string  // Option name as given, eg. "--foo".
+	Arg        string  // Raw argument.
+	Pos        int     // Position of the argument.
+	Candidates []*Flag // Options matching the abbreviation.
+}
+
+func (e *AmbiguousFlagError) Error() string {
+	names := make([]string, len(e.Candidates))
+	for i, flag := range e.Candidates {
+		names[i] = "--" + flag.Long
+	}
+	return fmt.Sprintf("multiple options matching %s: %s", e.Name, joinNames(names, "or"))
+}
+
+// MissingArgumentError is returned when an option requiring an argument is given without one.
+type MissingArgumentError struct {
+	Flag *Flag  // Option missing its argument.
+	Name string // Option name as given.
+	Arg  string // Raw argument.
+	Pos  int    // Position of the argument.
+}
+
+func (e *MissingArgumentError) Error() string {
+	return "missing argument for option " + e.Name
+}
+
+// UnexpectedArgumentError is returned when an option accepting no argument is given one.
+type UnexpectedArgumentError struct {
+	Flag  *Flag  // Option given an argument.
+	Name  string // Option name as given.
+	Value string // Unexpected option argument.
+	Arg   string // Raw argument.
+	Pos   int    // Position of the argument.
+}
+
+func (e *UnexpectedArgumentError) Error() string {
+	return fmt.Sprintf("unexpected argument '%s' for option %s", e.Value, e.Name)
+}
+
+// InvalidValueError is returned when an option argument is rejected by the flag
+// Value. Values read from an environment variable or a configuration file are
+// reported along with their source, rather than an argument and its position.
+type InvalidValueError struct {
+	Flag       *Flag  // Option whose value is invalid.
+	Name       string // Option name as given.
+	Value      string // Invalid option argument.
+	Arg        string // Raw argument.
+	Pos        int    // Position of the argument.
+	Env        string // Environment variable the value was read from, if any.
+	ConfigFile string // Configuration file the value was read from, if any.
+	Err        error  // Error returned by Value.Set.
+}
+
+func (e *InvalidValueError) Error() string {
+	name := e.Name
+	switch {
+	case e.Env != "":
+		name += " from environment variable " + e.Env
+	case e.ConfigFile != "":
+		name += " in config file " + e.ConfigFile
+	}
+	return fmt.Sprintf("invalid value %q for %s: %s", e.Value, name, valueErrorReason(e.Flag.Value, e.Err))
+}
+
+// Unwrap returns the error returned by Value.Set.
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+// valueErrorReason describes why a value was rejected. Parsing errors of
+// built-in values are reported with the expected type, rather than as
+// strconv or time errors.
+func valueErrorReason(value Value, err error) string {
+	switch value.(type) {
+	case *boolValue, *intValue, *float64Value, *durationValue, *numberValue, *intSliceValue, *float64SliceValue, *durationSliceValue:
+		return "expected " + strings.TrimPrefix(value.(TypedValue).Type(), "[]")
+	}
+	return err.Error()
+}
+
+// MissingRequiredError is returned when required options are not given.
+type MissingRequiredError struct {
+	Flags []*Flag // Missing options.
+}
+
+func (e *MissingRequiredError) Error() string {
+	names := make([]string, len(e.Flags))
+	for i, flag := range e.Flags {
+		names[i] = flagName(flag.Long, flag.Short)
+	}
+	if len(names) == 1 {
+		return "missing required option " + names[0]
+	}
+	return "missing required options " + strings.Join(names, ", ")
+}
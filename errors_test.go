@@ -0,0 +1,141 @@
+package flaq
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newErrorsFlagSet() *FlagSet {
+	var verbose, version bool
+	var name string
+	var port int
+
+	flags := &FlagSet{Abbreviations: true}
+	flags.Bool(&verbose, "verbose", "v", "", false)
+	flags.Bool(&version, "version", "", "", false)
+	flags.String(&name, "name", "n", "")
+	flags.Int(&port, "port", "p", "")
+	return flags
+}
+
+func TestUnknownFlagError(t *testing.T) {
+	err := newErrorsFlagSet().Parse([]string{"-v", "--verbsoe=true"})
+
+	var e *UnknownFlagError
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, "--verbsoe", e.Name)
+	assert.Equal(t, "--verbsoe=true", e.Arg)
+	assert.Equal(t, 1, e.Pos)
+	assert.Equal(t, []string{"--verbose"}, e.Suggestions)
+
+	err = newErrorsFlagSet().Parse([]string{"-vx"})
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, "-x", e.Name)
+	assert.Equal(t, "-vx", e.Arg)
+	assert.Equal(t, 0, e.Pos)
+	require.EqualError(t, err, "unknown option -x")
+}
+
+func TestAmbiguousFlagError(t *testing.T) {
+	err := newErrorsFlagSet().Parse([]string{"--ver"})
+
+	var e *AmbiguousFlagError
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, "--ver", e.Name)
+	require.Len(t, e.Candidates, 2)
+	assert.Equal(t, "verbose", e.Candidates[0].Long)
+	assert.Equal(t, "version", e.Candidates[1].Long)
+}
+
+func TestMissingArgumentError(t *testing.T) {
+	for _, arg := range []string{"--name", "-n"} {
+		err := newErrorsFlagSet().Parse([]string{"-v", arg})
+
+		var e *MissingArgumentError
+		require.True(t, errors.As(err, &e), arg)
+		assert.Equal(t, "name", e.Flag.Long)
+		assert.Equal(t, arg, e.Name)
+		assert.Equal(t, 1, e.Pos)
+		require.EqualError(t, err, "missing argument for option "+arg)
+	}
+}
+
+func TestUnexpectedArgumentError(t *testing.T) {
+	err := newErrorsFlagSet().Parse([]string{"--verbose=yes"})
+
+	var e *UnexpectedArgumentError
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, "verbose", e.Flag.Long)
+	assert.Equal(t, "yes", e.Value)
+	assert.Equal(t, "--verbose=yes", e.Arg)
+}
+
+func TestInvalidValueError(t *testing.T) {
+	err := newErrorsFlagSet().Parse([]string{"--name", "joe", "-p", "abc"})
+
+	var e *InvalidValueError
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, "port", e.Flag.Long)
+	assert.Equal(t, "-p", e.Name)
+	assert.Equal(t, "abc", e.Value)
+	assert.Equal(t, "-p", e.Arg)
+	assert.Equal(t, 2, e.Pos)
+	require.Error(t, e.Err)
+}
+
+func TestErrorPosSubcommand(t *testing.T) {
+	root := &Command{Name: "tool"}
+	root.Flags.Add(&Flag{Long: "verbose", Value: new(boolValue), Persistent: true})
+	remote := &Command{Name: "remote"}
+	remote.Add(&Command{Name: "add"})
+	root.Add(remote)
+
+	_, err := root.Parse([]string{"--verbose", "remote", "add", "--nope"})
+	var e *UnknownFlagError
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, 3, e.Pos)
+
+	_, err = root.Parse([]string{"remote", "--verbose", "ad"})
+	var c *UnknownCommandError
+	require.True(t, errors.As(err, &c))
+	assert.Equal(t, "ad", c.Name)
+	assert.Equal(t, 2, c.Pos)
+	assert.Equal(t, []string{"add"}, c.Suggestions)
+}
+
+func TestUnknownFlagErrorConfigFile(t *testing.T) {
+	flags := newErrorsFlagSet()
+	flags.ConfigFile = "testdata/unknown.json"
+	err := flags.Parse([]string{})
+
+	var e *UnknownFlagError
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, "--nme", e.Name)
+	assert.Equal(t, "testdata/unknown.json", e.ConfigFile)
+	assert.Equal(t, []string{"--name"}, e.Suggestions)
+}
+
+func TestMissingRequiredError(t *testing.T) {
+	flags := newErrorsFlagSet()
+	flags.Lookup("name").Required = true
+	flags.Lookup("port").Required = true
+	err := flags.Parse([]string{})
+
+	var e *MissingRequiredError
+	require.True(t, errors.As(err, &e))
+	require.Len(t, e.Flags, 2)
+	require.EqualError(t, err, "missing required options --name, --port")
+}
+
+func TestInvalidValueErrorMessage(t *testing.T) {
+	err := newErrorsFlagSet().Parse([]string{"--port", "abc"})
+	require.EqualError(t, err, `invalid value "abc" for --port: expected int`)
+
+	var numErr *strconv.NumError
+	require.True(t, errors.As(err, &numErr))
+	require.Equal(t, numErr, errors.Unwrap(err))
+}
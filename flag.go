@@ -1,9 +1,12 @@
 package flaq
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"runtime/debug"
 	"strings"
 	"time"
 )
@@ -13,7 +16,7 @@ type errorHandling int
 // These constants cause FlagSet.Parse to behave as described if the parse fails.
 const (
 	ContinueOnError errorHandling = iota // Return a descriptive error.
-	ExitOnError                          // Print a descriptive error to os.Stderr and call os.Exit(2).
+	ExitOnError                          // Print a descriptive error to ErrOutput and call Exit(2).
 	PanicOnError                         // Call panic with a descriptive error.
 )
 
@@ -54,11 +57,57 @@ func Duration(dvar *time.Duration, long, short, description string) {
 	flags.Duration(dvar, long, short, description)
 }
 
+// StringSlice adds a string slice flag with specified long/short form and description.
+// Values are appended on every occurrence of the flag. split indicates whether
+// values are also split on commas (eg. --flag=a,b).
+func StringSlice(svar *[]string, long, short, description string, split bool) {
+	flags.StringSlice(svar, long, short, description, split)
+}
+
+// IntSlice adds an int slice flag with specified long/short form and description.
+// Values are appended on every occurrence of the flag. split indicates whether
+// values are also split on commas (eg. --flag=1,2).
+func IntSlice(ivar *[]int, long, short, description string, split bool) {
+	flags.IntSlice(ivar, long, short, description, split)
+}
+
+// Float64Slice adds a float64 slice flag with specified long/short form and description.
+// Values are appended on every occurrence of the flag. split indicates whether
+// values are also split on commas (eg. --flag=1.5,2.5).
+func Float64Slice(fvar *[]float64, long, short, description string, split bool) {
+	flags.Float64Slice(fvar, long, short, description, split)
+}
+
+// DurationSlice adds a duration slice flag with specified long/short form and description.
+// Values are appended on every occurrence of the flag. split indicates whether
+// values are also split on commas (eg. --flag=1s,5m).
+func DurationSlice(dvar *[]time.Duration, long, short, description string, split bool) {
+	flags.DurationSlice(dvar, long, short, description, split)
+}
+
+// StringMap adds a key=value flag with specified long/short form and description.
+// Pairs are collected across repeated occurrences of the flag (eg. --label env=prod
+// --label team=core). unique indicates whether duplicate keys are rejected.
+func StringMap(mvar *map[string]string, long, short, description string, unique bool) {
+	flags.StringMap(mvar, long, short, description, unique)
+}
+
+// Enum adds a string flag with specified long/short form and description,
+// whose value is restricted to the given choices.
+func Enum(svar *string, long, short, description string, choices ...string) {
+	flags.Enum(svar, long, short, description, choices...)
+}
+
 // Help sets the help flag's long/short form and description.
 func Help(long, short, description string) {
 	flags.Help(long, short, description)
 }
 
+// Version sets the version flag's long/short form and version text.
+func Version(long, short, text string) {
+	flags.Version(long, short, text)
+}
+
 // Struct adds flags using reflection and struct field tags.
 func Struct(svar interface{}) {
 	flags.Struct(svar)
@@ -69,6 +118,11 @@ func Add(flag *Flag) {
 	flags.Add(flag)
 }
 
+// Validate checks the flags definitions, see FlagSet.Validate.
+func Validate() error {
+	return flags.Validate()
+}
+
 // Parse parses flag definitions from the argument list, which should not
 // include the command name. Must be called after all flags in the FlagSet
 // are defined and before flags are accessed by the program.
@@ -81,6 +135,23 @@ func Args() []string {
 	return flags.Args()
 }
 
+// Lookup returns the flag with the given long or short name, or nil if there is none.
+func Lookup(name string) *Flag {
+	return flags.Lookup(name)
+}
+
+// Changed reports whether the flag with the given long or short name
+// was set on the command line.
+func Changed(name string) bool {
+	return flags.Changed(name)
+}
+
+// Visit visits the flags set on the command line, calling fn for each,
+// in the order they were first seen.
+func Visit(fn func(*Flag)) {
+	flags.Visit(fn)
+}
+
 // Flag is a representation for a command line option.
 type Flag struct {
 	// Long option name.
@@ -108,9 +179,33 @@ type Flag struct {
 	// Usage can be set to overwrite the option usage line in the help usage.
 	Usage string
 
+	// Complete returns completion candidates for the option argument.
+	Complete CompleteFunc
+
+	// DefValue is the default value as text, as it will appear in help usage.
+	// When empty, it is set by FlagSet.Add from the initial value, if Value
+	// implements TypedValue.
+	DefValue string
+
 	// Final indicates that option parsing should stop when the option is seen.
 	// This is generally not to be used, except for some options such as --help or --version.
 	Final bool
+
+	// Persistent indicates that the option is inherited by subcommands.
+	Persistent bool
+
+	// Required indicates that the option must be given, either on the command line,
+	// through an environment variable or in a configuration file.
+	Required bool
+
+	// Env is the name of an environment variable the option value is read from
+	// when the option is not given on the command line. It is prefixed with
+	// the FlagSet EnvPrefix.
+	Env string
+
+	// Group is the help usage section the option is listed in. Options
+	// without a group are listed in the "Options" section.
+	Group string
 }
 
 // FlagArg represents a flag argument. An empty Default indicates that the argument is required.
@@ -120,7 +215,8 @@ type FlagArg struct {
 }
 
 // A FlagSet represents a set of defined flags. The zero value of a FlagSet
-// has ContinueOnError error handling and doesn't add a help flag.
+// has ContinueOnError error handling: Parse returns errors, as well as ErrHelp
+// once help usage is written.
 type FlagSet struct {
 	// Abbreviations indicates that option abbreviations are supported.
 	Abbreviations bool
@@ -135,17 +231,64 @@ type FlagSet struct {
 	// This means that the parsing will stop when an operand is seen.
 	Ordered bool
 
+	// EnvPrefix is prepended to the environment variable name of every flag.
+	EnvPrefix string
+
+	// ConfigFile is the path of a configuration file to load option values from.
+	// It can be set in code, or through the flag added by Config. The file format
+	// is inferred from its extension: .json, .ini, or one registered with
+	// RegisterConfigFormat.
+	// Options given on the command line or through environment variables take
+	// precedence over the configuration file.
+	ConfigFile string
+
+	// CompleteArgs returns completion candidates for operands.
+	// By default, operands are completed as file paths.
+	CompleteArgs CompleteFunc
+
+	// KeepOrder indicates that flags are listed in help usage in the order
+	// they were added, rather than sorted by name.
+	KeepOrder bool
+
 	// UsageLine can be set to overwrite the flag help usage.
 	UsageLine string
 
 	// UsageFunc
 	UsageFunc func(*FlagSet) string
 
+	// Output is where help usage, version text and completion candidates are written.
+	// It defaults to os.Stdout.
+	Output io.Writer
+
+	// ErrOutput is where errors are written with ExitOnError error handling.
+	// It defaults to os.Stderr.
+	ErrOutput io.Writer
+
+	// Exit is called to exit the program once help usage, version text or
+	// completion candidates are written, or on errors with ExitOnError error
+	// handling.
+	// It defaults to os.Exit.
+	Exit func(code int)
+
 	flags    []*Flag
 	seenArgs []string
 	args     []string
 	help     bool
 	helpFlag *Flag
+	version  bool
+	command  *Command
+	actual   map[*Flag]int
+	seen     []*Flag
+	provided map[*Flag]bool
+
+	constraints []constraint
+	versionFlag *Flag
+	versionText string
+
+	argc   int    // Number of arguments given to Parse.
+	arg    string // Argument being parsed.
+	pos    int    // Position of the argument being parsed.
+	offset int    // Position of the subcommand arguments in the original argument list.
 }
 
 // String adds a string flag with specified long/short form and description.
@@ -217,6 +360,83 @@ func (f *FlagSet) Duration(dvar *time.Duration, long, short, description string)
 	})
 }
 
+// StringSlice adds a string slice flag with specified long/short form and description.
+// Values are appended on every occurrence of the flag. split indicates whether
+// values are also split on commas (eg. --flag=a,b).
+func (f *FlagSet) StringSlice(svar *[]string, long, short, description string, split bool) {
+	f.Add(&Flag{
+		Long:        long,
+		Short:       short,
+		Description: description,
+		Value:       &stringSliceValue{value: svar, split: split},
+		Arg:         &FlagArg{Name: "string"},
+	})
+}
+
+// IntSlice adds an int slice flag with specified long/short form and description.
+// Values are appended on every occurrence of the flag. split indicates whether
+// values are also split on commas (eg. --flag=1,2).
+func (f *FlagSet) IntSlice(ivar *[]int, long, short, description string, split bool) {
+	f.Add(&Flag{
+		Long:        long,
+		Short:       short,
+		Description: description,
+		Value:       &intSliceValue{value: ivar, split: split},
+		Arg:         &FlagArg{Name: "int"},
+	})
+}
+
+// Float64Slice adds a float64 slice flag with specified long/short form and description.
+// Values are appended on every occurrence of the flag. split indicates whether
+// values are also split on commas (eg. --flag=1.5,2.5).
+func (f *FlagSet) Float64Slice(fvar *[]float64, long, short, description string, split bool) {
+	f.Add(&Flag{
+		Long:        long,
+		Short:       short,
+		Description: description,
+		Value:       &float64SliceValue{value: fvar, split: split},
+		Arg:         &FlagArg{Name: "float"},
+	})
+}
+
+// DurationSlice adds a duration slice flag with specified long/short form and description.
+// Values are appended on every occurrence of the flag. split indicates whether
+// values are also split on commas (eg. --flag=1s,5m).
+func (f *FlagSet) DurationSlice(dvar *[]time.Duration, long, short, description string, split bool) {
+	f.Add(&Flag{
+		Long:        long,
+		Short:       short,
+		Description: description,
+		Value:       &durationSliceValue{value: dvar, split: split},
+		Arg:         &FlagArg{Name: "duration"},
+	})
+}
+
+// StringMap adds a key=value flag with specified long/short form and description.
+// Pairs are collected across repeated occurrences of the flag (eg. --label env=prod
+// --label team=core). unique indicates whether duplicate keys are rejected.
+func (f *FlagSet) StringMap(mvar *map[string]string, long, short, description string, unique bool) {
+	f.Add(&Flag{
+		Long:        long,
+		Short:       short,
+		Description: description,
+		Value:       &stringMapValue{value: mvar, unique: unique},
+		Arg:         &FlagArg{Name: "key=value"},
+	})
+}
+
+// Enum adds a string flag with specified long/short form and description,
+// whose value is restricted to the given choices.
+func (f *FlagSet) Enum(svar *string, long, short, description string, choices ...string) {
+	f.Add(&Flag{
+		Long:        long,
+		Short:       short,
+		Description: description,
+		Value:       &enumValue{value: svar, choices: choices},
+		Arg:         &FlagArg{},
+	})
+}
+
 // Help sets the help flag's long/short form and description.
 func (f *FlagSet) Help(long, short, description string) {
 	helpFlag := &Flag{
@@ -227,61 +447,120 @@ func (f *FlagSet) Help(long, short, description string) {
 		Final:       true,
 	}
 	if f.helpFlag == nil {
-		flags.Add(helpFlag)
+		f.Add(helpFlag)
 		f.helpFlag = helpFlag
 	} else {
 		*f.helpFlag = *helpFlag
 	}
 }
 
-// Struct adds flags using reflection and struct field tags.
+// Version sets the version flag's long/short form and version text. When the
+// flag is seen, parsing stops and the version text is written to Output. An
+// empty text is replaced by the program name and the main module version,
+// as recorded in the binary build information.
+func (f *FlagSet) Version(long, short, text string) {
+	versionFlag := &Flag{
+		Long:        long,
+		Short:       short,
+		Description: "show version information",
+		Value:       (*boolValue)(&f.version),
+		Final:       true,
+	}
+	f.versionText = text
+	if f.versionFlag == nil {
+		f.Add(versionFlag)
+		f.versionFlag = versionFlag
+	} else {
+		*f.versionFlag = *versionFlag
+	}
+}
+
+// versionString returns the text written when the version flag is seen.
+func (f *FlagSet) versionString() string {
+	if f.versionText != "" {
+		return f.versionText
+	}
+	version := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		version = info.Main.Version
+	}
+	return f.program() + " " + version
+}
+
+// defaultHelp adds the --help and -h help flag, unless these names are
+// already used by other flags.
+func (f *FlagSet) defaultHelp() {
+	long, short := "help", "h"
+	if f.Lookup(long) != nil {
+		long = ""
+	}
+	if f.Lookup(short) != nil {
+		short = ""
+	}
+	if long != "" || short != "" {
+		f.Help(long, short, "show usage help")
+	}
+}
+
+// Struct adds flags using reflection and struct field tags. Fields of embedded
+// structs are added as if they were fields of the parent struct, while fields
+// of nested structs are prefixed and listed in their own help usage section.
 func (f *FlagSet) Struct(svar interface{}) {
 	sval := reflect.ValueOf(svar)
 	if svar == nil || sval.Kind() != reflect.Ptr || sval.Elem().Kind() != reflect.Struct {
 		panic("can only accept a pointer to a struct")
 	}
-	sval = sval.Elem()
-
-	for i := 0; i < sval.NumField(); i++ {
-		field := sval.Field(i)
-		val := field.Addr().Interface()
-		tag, ok := sval.Type().Field(i).Tag.Lookup("flaq")
-		if !ok {
-			continue
-		}
-		flag, fieldType := parseStructFieldTag(tag)
-		switch fieldType {
-		case "count":
-			flag.Value = (*countValue)(val.(*int))
-		case "duration":
-			flag.Value = (*durationValue)(val.(*time.Duration))
-			flag.Arg = &FlagArg{Name: "duration"}
-		case "float64":
-			flag.Value = (*float64Value)(val.(*float64))
-			flag.Arg = &FlagArg{Name: "float"}
-		case "int":
-			flag.Value = (*intValue)(val.(*int))
-			flag.Arg = &FlagArg{Name: "int"}
-		case "string":
-			flag.Value = (*stringValue)(val.(*string))
-			flag.Arg = &FlagArg{Name: "string"}
-		case "bool":
-			flag.Value = (*boolValue)(val.(*bool))
-			flag.Arg = &FlagArg{Default: "true", Name: "bool"}
-		case "":
-			flag.Value = (*boolValue)(val.(*bool))
-		default:
-			panic(fmt.Sprintf(`unknown struct field type "%s"`, fieldType))
-		}
-		f.Add(flag)
-	}
+	f.structFlags(sval.Elem(), "", "")
 }
 
-// Add adds a flag to the flagset.
+// Add adds a flag to the flagset. It panics if the flag is invalid or its
+// names are already used, as reported by Validate.
 func (f *FlagSet) Add(flag *Flag) {
+	if err := checkFlag(flag, f.flags); err != nil {
+		panic(err.Error())
+	}
+	if v, ok := flag.Value.(TypedValue); ok && flag.DefValue == "" {
+		flag.DefValue = v.String()
+	}
 	f.flags = append(f.flags, flag)
 }
 
+// Validate checks the flags of the flagset, and reports invalid or duplicate
+// names, as well as flags without Value. Add panics on such flags, while
+// Validate also catches flags modified once added. Flag constraints naming
+// undefined flags are reported as well.
+func (f *FlagSet) Validate() error {
+	for i, flag := range f.flags {
+		if err := checkFlag(flag, f.flags[:i]); err != nil {
+			return err
+		}
+	}
+	return f.checkConstraintNames()
+}
+
+// checkFlag returns an error if a flag is invalid, or if its names are used by other flags.
+func checkFlag(flag *Flag, flags []*Flag) error {
+	switch {
+	case flag.Long == "" && flag.Short == "":
+		return errors.New("option without long nor short name")
+	case flag.Long != "" && (flag.Long[0] == '-' || strings.ContainsAny(flag.Long, "= ")):
+		return fmt.Errorf("invalid option name %q, names must not start with - nor contain = or spaces", flag.Long)
+	case flag.Short != "" && (len(flag.Short) != 1 || strings.ContainsAny(flag.Short, "-= ")):
+		return fmt.Errorf("invalid short option name %q, short names must be a single character other than - or =", flag.Short)
+	case flag.Value == nil:
+		return fmt.Errorf("option %s has no Value", flagName(flag.Long, flag.Short))
+	}
+	for _, other := range flags {
+		if flag.Long != "" && flag.Long == other.Long {
+			return fmt.Errorf("duplicate option --%s", flag.Long)
+		}
+		if flag.Short != "" && flag.Short == other.Short {
+			return fmt.Errorf("duplicate option -%s", flag.Short)
+		}
+	}
+	return nil
+}
+
 // Usage returns help usage.
 func (f *FlagSet) Usage() string {
 	if f.UsageFunc != nil {
@@ -292,12 +571,23 @@ func (f *FlagSet) Usage() string {
 
 // Parse parses flag definitions from the argument list, which should not
 // include the command name. Must be called after all flags in the FlagSet
-// are defined and before flags are accessed by the program.
+// are defined and before flags are accessed by the program. Flags set by
+// a previous call are forgotten, as well as its remaining arguments.
+//
+// Command-line parsing errors are of type *UnknownFlagError, *AmbiguousFlagError,
+// *MissingArgumentError, *UnexpectedArgumentError or *InvalidValueError, and a
+// *MissingRequiredError is returned when required flags are not given.
 func (f *FlagSet) Parse(args []string) error {
 	if f.helpFlag == nil && !f.DisableHelp {
-		flags.Help("help", "h", "show usage help")
+		f.defaultHelp()
+	}
+	if len(args) > 0 && args[0] == completeArg {
+		// Completion scripts call the program back with the words typed so far.
+		f.printCompletion(args[1:])
+		return f.fail(ErrComplete)
 	}
-	f.args = args
+	f.args, f.argc, f.help, f.version = args, len(args), false, false
+	f.seenArgs, f.actual, f.seen, f.provided = nil, nil, nil, nil
 	for {
 		seen, err := f.parseOne()
 		if seen {
@@ -305,27 +595,198 @@ func (f *FlagSet) Parse(args []string) error {
 		}
 		if err == nil {
 			if f.help {
-				// When the default --help flag is encountered, print help usage
-				// to stdout and exit. To change this behaviour, one should set
-				// DisableHelp and implement their own help flag instead.
-				fmt.Print(f.Usage())
-				os.Exit(0)
+				// When the default --help flag is encountered, help usage is
+				// written to Output. Parse then exits, or returns ErrHelp with
+				// ContinueOnError error handling. To change this behaviour, one
+				// should set DisableHelp and implement their own help flag instead.
+				fmt.Fprint(f.output(), f.Usage())
+				return f.fail(ErrHelp)
+			}
+			if f.version {
+				fmt.Fprintln(f.output(), f.versionString())
+				return f.fail(ErrVersion)
 			}
 			break
 		}
-		switch f.ErrorHandling {
-		case ContinueOnError:
-			return err
-		case ExitOnError:
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(2)
-		case PanicOnError:
-			panic(err)
+		return f.fail(err)
+	}
+	if err := f.parseConfig(); err != nil {
+		return f.fail(err)
+	}
+	if err := f.parseEnv(); err != nil {
+		return f.fail(err)
+	}
+	if err := f.check(); err != nil {
+		return f.fail(err)
+	}
+	return nil
+}
+
+// set sets the value of a flag seen on the command line, given by name. It
+// records how many times the flag was seen, and in which order flags were seen.
+func (f *FlagSet) set(flag *Flag, name, val string) error {
+	if f.occurrences(flag) == 0 {
+		// The command line takes precedence over environment variables
+		// and configuration files.
+		resetValue(flag.Value)
+	}
+	if err := flag.Value.Set(val); err != nil {
+		return &InvalidValueError{Flag: flag, Name: name, Value: val, Arg: f.arg, Pos: f.pos, Err: err}
+	}
+	if f.actual == nil {
+		f.actual = make(map[*Flag]int)
+	}
+	if f.actual[flag] == 0 {
+		f.seen = append(f.seen, flag)
+	}
+	f.actual[flag]++
+	return nil
+}
+
+// provide records that a flag value was provided by a configuration file
+// or an environment variable.
+func (f *FlagSet) provide(flag *Flag) {
+	if f.provided == nil {
+		f.provided = make(map[*Flag]bool)
+	}
+	f.provided[flag] = true
+}
+
+// given reports whether a flag was set on the command line, through an
+// environment variable or in a configuration file. A persistent flag
+// inherited by a subcommand may also have been given to an ancestor.
+func (f *FlagSet) given(flag *Flag) bool {
+	return f.actual[flag] > 0 || f.provided[flag] || f.givenToAncestor(flag)
+}
+
+// givenToAncestor reports whether a persistent flag inherited by a subcommand
+// was given to one of its ancestors.
+func (f *FlagSet) givenToAncestor(flag *Flag) bool {
+	parent := f.inheritedFrom(flag)
+	return parent != nil && parent.given(flag)
+}
+
+// occurrences returns how many times a flag was set on the command line,
+// including before the subcommand name for an inherited persistent flag.
+func (f *FlagSet) occurrences(flag *Flag) int {
+	n := f.actual[flag]
+	if parent := f.inheritedFrom(flag); parent != nil {
+		n += parent.occurrences(flag)
+	}
+	return n
+}
+
+// inheritedFrom returns the flags of the parent command a persistent flag
+// may be inherited from, or nil.
+func (f *FlagSet) inheritedFrom(flag *Flag) *FlagSet {
+	if !flag.Persistent {
+		return nil
+	}
+	return f.parent()
+}
+
+// parent returns the flags of the parent command, or nil.
+func (f *FlagSet) parent() *FlagSet {
+	if f.command == nil || f.command.parent == nil {
+		return nil
+	}
+	return &f.command.parent.Flags
+}
+
+// handsOff reports whether a subcommand takes over the remaining arguments.
+func (f *FlagSet) handsOff() bool {
+	return f.command != nil && f.command.subcommand() != nil
+}
+
+// check verifies that required flags were given and that flag constraints
+// are satisfied. Nothing is checked when parsing stopped on a final flag
+// such as --help, nor when a subcommand takes over, as the subcommand
+// checks its own flags and the persistent ones it inherits.
+func (f *FlagSet) check() error {
+	for _, flag := range f.flags {
+		if f.actual[flag] > 0 && flag.Final {
+			return nil
 		}
 	}
+	if f.handsOff() {
+		return nil
+	}
+	if err := f.checkRequired(); err != nil {
+		return err
+	}
+	return f.checkConstraints()
+}
+
+// checkRequired returns an error listing the required flags that were not given.
+func (f *FlagSet) checkRequired() error {
+	var missing []*Flag
+	for _, flag := range f.flags {
+		if flag.Required && !f.given(flag) {
+			missing = append(missing, flag)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingRequiredError{Flags: missing}
+	}
 	return nil
 }
 
+// fail handles a parsing error according to the FlagSet error handling.
+func (f *FlagSet) fail(err error) error {
+	switch f.ErrorHandling {
+	case ExitOnError:
+		if err == ErrHelp || err == ErrVersion || err == ErrComplete {
+			f.exit(0)
+			break
+		}
+		fmt.Fprintln(f.errOutput(), err)
+		f.exit(2)
+	case PanicOnError:
+		panic(err)
+	}
+	return err
+}
+
+// output returns the writer help usage is written to.
+func (f *FlagSet) output() io.Writer {
+	if f.Output == nil {
+		return os.Stdout
+	}
+	return f.Output
+}
+
+// errOutput returns the writer errors are written to.
+func (f *FlagSet) errOutput() io.Writer {
+	if f.ErrOutput == nil {
+		return os.Stderr
+	}
+	return f.ErrOutput
+}
+
+// exit exits the program with the given status code.
+func (f *FlagSet) exit(code int) {
+	if f.Exit == nil {
+		os.Exit(code)
+	}
+	f.Exit(code)
+}
+
+// ordered reports whether parsing stops when an operand is seen. This is
+// the case when the FlagSet belongs to a command having subcommands.
+func (f *FlagSet) ordered() bool {
+	return f.Ordered || f.command != nil && len(f.command.commands) > 0
+}
+
+// hasFlag reports whether the flag, or a flag sharing one of its names, is defined.
+func (f *FlagSet) hasFlag(flag *Flag) bool {
+	for _, fl := range f.flags {
+		if fl == flag || flag.Long != "" && fl.Long == flag.Long || flag.Short != "" && fl.Short == flag.Short {
+			return true
+		}
+	}
+	return false
+}
+
 // parseOne parses one flag. It reports whether a flag was seen.
 func (f *FlagSet) parseOne() (bool, error) {
 	if len(f.args) == 0 {
@@ -334,13 +795,13 @@ func (f *FlagSet) parseOne() (bool, error) {
 
 	arg := f.args[0]
 	if len(arg) < 2 || arg[0] != '-' {
-		if !f.Ordered {
+		if !f.ordered() {
 			f.seenArgs, f.args = append(f.seenArgs, arg), f.args[1:]
 			return f.parseOne()
 		}
 		return false, nil
 	}
-	f.args = f.args[1:]
+	f.args, f.arg, f.pos = f.args[1:], arg, f.offset+f.argc-len(f.args)
 
 	if arg[1] == '-' {
 		if len(arg) == 2 {
@@ -379,30 +840,30 @@ func (f *FlagSet) parseLong(name string) (bool, error) {
 
 	switch len(candidates) {
 	case 0:
-		return false, fmt.Errorf("unknown option --%s", name)
+		return false, &UnknownFlagError{Name: "--" + name, Arg: f.arg, Pos: f.pos, Suggestions: f.suggest(name)}
 	case 1:
 		flag := candidates[0]
 
 		if hasFlagArg {
 			if flag.Arg == nil {
-				return false, fmt.Errorf("unexpected argument '%s' for option --%s", flagArg, name)
+				return false, &UnexpectedArgumentError{Flag: flag, Name: "--" + name, Value: flagArg, Arg: f.arg, Pos: f.pos}
 			}
 		} else if flag.Arg != nil {
 			if flag.Arg.Default != "" {
 				flagArg = flag.Arg.Default
 			} else if len(f.args) == 0 {
-				return false, fmt.Errorf("missing argument for option --%s", name)
+				return false, &MissingArgumentError{Flag: flag, Name: "--" + name, Arg: f.arg, Pos: f.pos}
 			} else {
 				flagArg, f.args = f.args[0], f.args[1:]
 			}
 		}
 
-		if err := flag.Value.Set(flagArg); err != nil {
+		if err := f.set(flag, "--"+name, flagArg); err != nil {
 			return false, err
 		}
 		return !flag.Final, nil
 	}
-	return false, fmt.Errorf("multiple options matching --%s", name)
+	return false, &AmbiguousFlagError{Name: "--" + name, Arg: f.arg, Pos: f.pos, Candidates: candidates}
 }
 
 func (f *FlagSet) parseShort(name string) (bool, error) {
@@ -413,31 +874,31 @@ func (f *FlagSet) parseShort(name string) (bool, error) {
 
 		case len(name) > 1:
 			if flag.Arg == nil {
-				if err := flag.Value.Set(""); err != nil {
+				if err := f.set(flag, "-"+flag.Short, ""); err != nil {
 					return false, err
 				}
 				return f.parseShort(name[1:])
 			}
-			if err := flag.Value.Set(name[1:]); err != nil {
+			if err := f.set(flag, "-"+flag.Short, name[1:]); err != nil {
 				return false, err
 			}
 
 		default:
 			if flag.Arg != nil && flag.Arg.Default == "" {
 				if len(f.args) == 0 {
-					return false, fmt.Errorf("missing value for option -%c", name[0])
+					return false, &MissingArgumentError{Flag: flag, Name: "-" + flag.Short, Arg: f.arg, Pos: f.pos}
 				}
-				if err := flag.Value.Set(f.args[0]); err != nil {
+				if err := f.set(flag, "-"+flag.Short, f.args[0]); err != nil {
 					return false, err
 				}
 				f.args = f.args[1:]
-			} else if err := flag.Value.Set(""); err != nil {
+			} else if err := f.set(flag, "-"+flag.Short, ""); err != nil {
 				return false, err
 			}
 		}
 		return !flag.Final, nil
 	}
-	return false, fmt.Errorf("unknown option -%c", name[0])
+	return false, &UnknownFlagError{Name: "-" + name[:1], Arg: f.arg, Pos: f.pos}
 }
 
 // Args returns the remaining arguments once options have been parsed.
@@ -445,6 +906,25 @@ func (f *FlagSet) Args() []string {
 	return append(f.seenArgs, f.args...)
 }
 
+// Lookup returns the flag with the given long or short name, or nil if there is none.
+func (f *FlagSet) Lookup(name string) *Flag {
+	for _, flag := range f.flags {
+		if name != "" && (flag.Long == name || flag.Short == name) {
+			return flag
+		}
+	}
+	return nil
+}
+
+// flagName returns the name of an option as it appears on the command line,
+// preferring the long form.
+func flagName(long, short string) string {
+	if long != "" {
+		return "--" + long
+	}
+	return "-" + short
+}
+
 // VisitAll visits all the flags, calling fn for each. It visits all flags, even those not set.
 func (f *FlagSet) VisitAll(fn func(*Flag)) {
 	for _, flag := range f.flags {
@@ -452,45 +932,51 @@ func (f *FlagSet) VisitAll(fn func(*Flag)) {
 	}
 }
 
-func parseStructFieldTag(tag string) (*Flag, string) {
-	flag := &Flag{}
-	var fieldType string
-	var parsing *string
-	for j := 0; j < len(tag); j++ {
-		switch parsing {
-		case nil:
-			if tag[j] == '-' {
-				parsing = &flag.Short
-			}
-		case &flag.Short:
-			switch tag[j] {
-			case '-':
-				parsing = &flag.Long
-			case ',':
-				parsing = &flag.Long
-				j += 3
-			case ' ':
-				parsing = &fieldType
-			default:
-				flag.Short = string(tag[j])
-			}
-		case &flag.Long:
-			if tag[j] == ' ' {
-				parsing = &fieldType
-			} else {
-				flag.Long += string(tag[j])
-			}
-		case &fieldType:
-			if tag[j] == ' ' {
-				parsing = &flag.Description
-			} else {
-				fieldType += string(tag[j])
-			}
-		case &flag.Description:
-			if flag.Description != "" || tag[j] != ' ' {
-				flag.Description += string(tag[j])
+// Visit visits the flags set on the command line, calling fn for each,
+// in the order they were first seen. For a subcommand, persistent flags
+// set before the subcommand name are visited first.
+func (f *FlagSet) Visit(fn func(*Flag)) {
+	if f.command != nil && f.command.parent != nil {
+		f.command.parent.Flags.Visit(func(flag *Flag) {
+			if f.inherits(flag) {
+				fn(flag)
 			}
+		})
+	}
+	for _, flag := range f.seen {
+		if f.occurrences(flag) == f.actual[flag] {
+			fn(flag)
 		}
 	}
-	return flag, fieldType
+}
+
+// inherits reports whether a persistent flag of the parent command was
+// inherited, rather than replaced by a flag of the same name.
+func (f *FlagSet) inherits(flag *Flag) bool {
+	if !flag.Persistent {
+		return false
+	}
+	for _, other := range f.flags {
+		if other == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Changed reports whether the flag with the given long or short name
+// was set on the command line.
+func (f *FlagSet) Changed(name string) bool {
+	return f.Occurrences(name) > 0
+}
+
+// Occurrences returns how many times the flag with the given long or short
+// name was set on the command line. For a subcommand, this includes the
+// occurrences of persistent flags before the subcommand name.
+func (f *FlagSet) Occurrences(name string) int {
+	flag := f.Lookup(name)
+	if flag == nil {
+		return 0
+	}
+	return f.occurrences(flag)
 }
@@ -1,7 +1,11 @@
 package flaq
 
 import (
+	"bytes"
 	"fmt"
+	"net"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -187,7 +191,92 @@ func TestParseAmbiguousAbbreviation(t *testing.T) {
 	flags.Bool(&foo, "foo-foo", "", "", false)
 
 	err := flags.Parse([]string{"--foo"})
-	require.Error(t, err)
+	require.EqualError(t, err, "multiple options matching --foo: --foo-bar or --foo-foo")
+}
+
+func TestParseUnknownOptionSuggestion(t *testing.T) {
+	fixtures := []struct {
+		arg string
+		err string
+	}{
+		{"--verbos", "unknown option --verbos, did you mean --verbose?"},
+		{"--vrebose", "unknown option --vrebose, did you mean --verbose?"},
+		{"--colour", "unknown option --colour, did you mean --color?"},
+		{"--v", "unknown option --v, did you mean -v?"},
+		{"--dry", "unknown option --dry, did you mean --dry-run?"},
+		{"--d", "unknown option --d, did you mean --dry-run or --dir?"},
+		{"-x", "unknown option -x"},
+		{"--unrelated", "unknown option --unrelated"},
+		{"--secrt", "unknown option --secrt"},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.arg, func(t *testing.T) {
+			var verbose, dryRun, secret bool
+			var color, dir string
+
+			flags := &FlagSet{}
+			flags.Bool(&verbose, "verbose", "v", "", false)
+			flags.Bool(&dryRun, "dry-run", "", "", false)
+			flags.String(&color, "color", "", "")
+			flags.String(&dir, "dir", "", "")
+			flags.Add(&Flag{Long: "secret", Value: (*boolValue)(&secret), Hidden: true})
+
+			err := flags.Parse([]string{fixture.arg})
+			require.EqualError(t, err, fixture.err)
+		})
+	}
+}
+
+func TestParseRequired(t *testing.T) {
+	fixtures := []struct {
+		args []string
+		err  string
+	}{
+		{
+			args: []string{"--name=ok", "--port=80"},
+		},
+		{
+			args: []string{"--name=ok"},
+			err:  "missing required option --port",
+		},
+		{
+			args: []string{},
+			err:  "missing required options --name, --port",
+		},
+		{
+			args: []string{"--help"},
+		},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fmt.Sprintf("%q", fixture.args), func(t *testing.T) {
+			var name string
+			var port int
+			var help bool
+
+			flags := &FlagSet{DisableHelp: true}
+			flags.Add(&Flag{Long: "name", Value: (*stringValue)(&name), Arg: &FlagArg{}, Required: true})
+			flags.Add(&Flag{Long: "port", Value: (*intValue)(&port), Arg: &FlagArg{}, Required: true})
+			flags.Add(&Flag{Long: "help", Value: (*boolValue)(&help), Final: true})
+
+			err := flags.Parse(fixture.args)
+			if fixture.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, fixture.err)
+			}
+		})
+	}
+}
+
+func TestParseRequiredPanicOnError(t *testing.T) {
+	var name string
+
+	flags := &FlagSet{ErrorHandling: PanicOnError}
+	flags.Add(&Flag{Long: "name", Value: (*stringValue)(&name), Arg: &FlagArg{}, Required: true})
+
+	require.Panics(t, func() { flags.Parse([]string{}) })
 }
 
 func TestParseStruct(t *testing.T) {
@@ -200,6 +289,14 @@ func TestParseStruct(t *testing.T) {
 		Count    int           `flaq:"-c, --count count         whether to count or not"`
 		Duration time.Duration `flaq:"    --duration duration   whether to duration or not"`
 
+		Strings   []string        `flaq:"-s, --strings []string         whether to strings or not"`
+		Ints      []int           `flaq:"    --ints []int               whether to ints or not"`
+		Floats    []float64       `flaq:"    --floats []float64         whether to floats or not"`
+		Durations []time.Duration `flaq:"    --durations []duration     whether to durations or not"`
+
+		Labels map[string]string `flaq:"-l, --label map[string]string  whether to label or not"`
+		Format string            `flaq:"-f, --format json|yaml          whether to format or not"`
+
 		RandomJSONField string `json:"randomField"`
 	}{}
 
@@ -214,6 +311,15 @@ func TestParseStruct(t *testing.T) {
 		"--float64=3.14",
 		"--int=100",
 		"-ccc",
+		"-sa",
+		"--strings=b",
+		"--ints=1",
+		"--ints=2",
+		"--floats=1.5",
+		"--durations=1s",
+		"--label=env=prod",
+		"-lteam=core",
+		"-f", "yaml",
 	})
 	require.NoError(t, err)
 
@@ -224,6 +330,256 @@ func TestParseStruct(t *testing.T) {
 	require.Equal(t, 100, opts.Int)
 	require.Equal(t, 3, opts.Count)
 	require.Equal(t, 3.14, opts.Float64)
+	require.Equal(t, []string{"a", "b"}, opts.Strings)
+	require.Equal(t, []int{1, 2}, opts.Ints)
+	require.Equal(t, []float64{1.5}, opts.Floats)
+	require.Equal(t, []time.Duration{time.Second}, opts.Durations)
+	require.Equal(t, map[string]string{"env": "prod", "team": "core"}, opts.Labels)
+	require.Equal(t, "yaml", opts.Format)
+}
+
+type level int
+
+func (l *level) Set(val string) error {
+	switch val {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %s", val)
+	}
+	return nil
+}
+
+type userID string
+
+func TestParseStructCustomTypes(t *testing.T) {
+	RegisterType("user-id", func(field interface{}) Value {
+		return (*stringValue)(field.(*userID))
+	})
+	defer delete(structFieldTypes, "user-id")
+
+	var opts = struct {
+		IP       net.IP    `flaq:"--ip ip             listen address"`
+		Endpoint url.URL   `flaq:"--endpoint url      api endpoint"`
+		Since    time.Time `flaq:"--since time        start time"`
+		Level    level     `flaq:"--level level       log level"`
+		User     userID    `flaq:"--user user-id      user identifier"`
+	}{}
+
+	flags := &FlagSet{}
+	flags.Struct(&opts)
+
+	err := flags.Parse([]string{
+		"--ip=127.0.0.1",
+		"--endpoint=https://example.com/api",
+		"--since=2019-05-01T10:00:00Z",
+		"--level=high",
+		"--user=u42",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "127.0.0.1", opts.IP.String())
+	require.Equal(t, "example.com", opts.Endpoint.Host)
+	require.Equal(t, time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC), opts.Since)
+	require.Equal(t, level(2), opts.Level)
+	require.Equal(t, userID("u42"), opts.User)
+
+	require.Contains(t, flags.Usage(), "--ip <ip>")
+	require.Contains(t, flags.Usage(), "--user <user-id>")
+
+	err = flags.Parse([]string{"--ip=localhost"})
+	require.Error(t, err)
+
+	require.PanicsWithValue(t, "invalid tag of struct field IP: unknown type ip at position 5", func() {
+		var opts = struct {
+			IP string `flaq:"--ip ip  listen address"`
+		}{}
+		(&FlagSet{}).Struct(&opts)
+	})
+}
+
+type port uint16
+
+func TestParseStructInferredTypes(t *testing.T) {
+	var opts = struct {
+		Name     string            `flaq:"-n, --name   name of the person to greet"`
+		Yell     bool              `flaq:"    --yell   whether to yell or not"`
+		Retries  int8              `flaq:"    --retries"`
+		Size     int64             `flaq:"    --size"`
+		Port     port              `flaq:"    --port"`
+		Ratio    float32           `flaq:"    --ratio"`
+		Timeout  time.Duration     `flaq:"    --timeout"`
+		Tags     []string          `flaq:"    --tag"`
+		Labels   map[string]string `flaq:"    --label"`
+		IP       net.IP            `flaq:"    --ip"`
+		Loglevel level             `flaq:"    --level"`
+	}{}
+
+	flags := &FlagSet{}
+	flags.Struct(&opts)
+
+	err := flags.Parse([]string{
+		"-n", "joe",
+		"--yell",
+		"--retries=3",
+		"--size=5000000000",
+		"--port=8080",
+		"--ratio=0.5",
+		"--timeout=5s",
+		"--tag=a",
+		"--label=env=prod",
+		"--ip=::1",
+		"--level=low",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "joe", opts.Name)
+	require.True(t, opts.Yell)
+	require.Equal(t, int8(3), opts.Retries)
+	require.Equal(t, int64(5000000000), opts.Size)
+	require.Equal(t, port(8080), opts.Port)
+	require.Equal(t, float32(0.5), opts.Ratio)
+	require.Equal(t, 5*time.Second, opts.Timeout)
+	require.Equal(t, []string{"a"}, opts.Tags)
+	require.Equal(t, map[string]string{"env": "prod"}, opts.Labels)
+	require.Equal(t, "::1", opts.IP.String())
+	require.Equal(t, level(1), opts.Loglevel)
+
+	require.Contains(t, flags.Usage(), "--port <uint>")
+	require.Contains(t, flags.Usage(), "--ip <ip>")
+
+	err = flags.Parse([]string{"--retries=300"})
+	require.EqualError(t, err, `invalid value "300" for --retries: expected int8`)
+}
+
+func TestParseStructTypeMismatch(t *testing.T) {
+	require.PanicsWithValue(t, "struct field Port of type string cannot hold a flag of type int", func() {
+		var opts = struct {
+			Port string `flaq:"--port int  listen port"`
+		}{}
+		(&FlagSet{}).Struct(&opts)
+	})
+	require.PanicsWithValue(t, "struct field Port of type int cannot hold a flag of type int64", func() {
+		var opts = struct {
+			Port int `flaq:"--port int64  listen port"`
+		}{}
+		(&FlagSet{}).Struct(&opts)
+	})
+	require.PanicsWithValue(t, "cannot infer the flag type of struct field Ch of type chan int", func() {
+		var opts = struct {
+			Ch chan int `flaq:"--ch  a channel"`
+		}{}
+		(&FlagSet{}).Struct(&opts)
+	})
+}
+
+type DBOptions struct {
+	Host string `flaq:"--db-host  database host"`
+	Port int    `flaq:"--db-port  database port"`
+}
+
+func TestParseNestedStruct(t *testing.T) {
+	var opts = struct {
+		DBOptions
+		Verbose bool `flaq:"-v, --verbose  verbose output"`
+		Server  struct {
+			Port int `flaq:"--port  listen port"`
+			TLS  struct {
+				Cert string `flaq:"--cert  certificate file"`
+			}
+		}
+		Cache struct {
+			Size int `flaq:"--size  cache size"`
+		} `flaq:"prefix:  Caching"`
+		Metrics struct {
+			Port int `flaq:"--port  metrics port"`
+		} `flaq:"prefix:stats"`
+	}{}
+
+	flags := &FlagSet{}
+	flags.Struct(&opts)
+
+	err := flags.Parse([]string{
+		"--db-host=localhost",
+		"--db-port=5432",
+		"-v",
+		"--server-port=8080",
+		"--server-tls-cert=cert.pem",
+		"--size=10",
+		"--stats-port=9090",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "localhost", opts.Host)
+	require.Equal(t, 5432, opts.Port)
+	require.True(t, opts.Verbose)
+	require.Equal(t, 8080, opts.Server.Port)
+	require.Equal(t, "cert.pem", opts.Server.TLS.Cert)
+	require.Equal(t, 10, opts.Cache.Size)
+	require.Equal(t, 9090, opts.Metrics.Port)
+
+	expectedUsage := `Usage: flaq.test [options]
+
+Options
+      --db-host <string>      database host
+      --db-port <int>         database port
+  -h, --help                  show usage help
+  -v, --verbose               verbose output
+
+Server
+      --server-port <int>     listen port
+
+TLS
+      --server-tls-cert <string>   certificate file
+
+Caching
+      --size <int>            cache size
+
+Metrics
+      --stats-port <int>      metrics port
+`
+	require.Equal(t, expectedUsage, flags.Usage())
+}
+
+func TestKebabCase(t *testing.T) {
+	require.Equal(t, "server", kebabCase("Server"))
+	require.Equal(t, "db-options", kebabCase("DBOptions"))
+	require.Equal(t, "tls", kebabCase("TLS"))
+	require.Equal(t, "http-server-url", kebabCase("HTTPServerURL"))
+}
+
+func TestParseSlices(t *testing.T) {
+	var includes []string
+	var ports []int
+
+	flags := &FlagSet{}
+	flags.StringSlice(&includes, "include", "i", "", false)
+	flags.IntSlice(&ports, "port", "p", "", true)
+
+	err := flags.Parse([]string{"--include", "a", "-ib,c", "--port=80,443", "-p", "8080"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b,c"}, includes)
+	require.Equal(t, []int{80, 443, 8080}, ports)
+}
+
+func TestParseStringMap(t *testing.T) {
+	var labels map[string]string
+
+	flags := &FlagSet{}
+	flags.StringMap(&labels, "label", "l", "", true)
+
+	err := flags.Parse([]string{"--label", "env=prod", "-l", "team=core"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"env": "prod", "team": "core"}, labels)
+
+	err = flags.Parse([]string{"--label", "env=dev"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"env": "dev"}, labels)
+
+	err = flags.Parse([]string{"--label", "env=prod", "--label", "env=dev"})
+	require.EqualError(t, err, `invalid value "env=dev" for --label: duplicate key "env"`)
 }
 
 func TestParseStructFieldTag(t *testing.T) {
@@ -232,6 +588,8 @@ func TestParseStructFieldTag(t *testing.T) {
 		short       string
 		long        string
 		fieldType   string
+		env         string
+		required    bool
 		description string
 	}{
 		{
@@ -256,17 +614,121 @@ func TestParseStructFieldTag(t *testing.T) {
 			long:        "yell",
 			description: "whether to yell or not",
 		},
+		{
+			tag:         "-n, --name string env:NAME  name of the person",
+			short:       "n",
+			long:        "name",
+			fieldType:   "string",
+			env:         "NAME",
+			description: "name of the person",
+		},
+		{
+			tag:         "    --yell env:YELL  whether to yell or not",
+			long:        "yell",
+			env:         "YELL",
+			description: "whether to yell or not",
+		},
+		{
+			tag:         "-n, --name string required env:NAME  name of the person",
+			short:       "n",
+			long:        "name",
+			fieldType:   "string",
+			env:         "NAME",
+			required:    true,
+			description: "name of the person",
+		},
+	}
+
+	for _, fixture := range fixtures {
+		tag, err := parseStructFieldTag(fixture.tag)
+		require.NoError(t, err)
+		require.Equal(t, fixture.short, tag.flag.Short)
+		require.Equal(t, fixture.long, tag.flag.Long)
+		require.Equal(t, fixture.fieldType, tag.fieldType)
+		require.Equal(t, fixture.env, tag.flag.Env)
+		require.Equal(t, fixture.required, tag.flag.Required)
+		require.Equal(t, fixture.description, tag.flag.Description)
+	}
+}
+
+func TestParseStructFieldTagAttributes(t *testing.T) {
+	tag, err := parseStructFieldTag("-p, --port int default:8080 env:PORT hidden placeholder:number group:'Network Options'  listen port")
+	require.NoError(t, err)
+	require.Equal(t, "int", tag.fieldType)
+	require.True(t, tag.hasDefault)
+	require.Equal(t, "8080", tag.defValue)
+	require.Equal(t, "PORT", tag.flag.Env)
+	require.True(t, tag.flag.Hidden)
+	require.Equal(t, "number", tag.placeholder)
+	require.Equal(t, "Network Options", tag.flag.Group)
+	require.Equal(t, "listen port", tag.flag.Description)
+}
+
+func TestParseStructFieldTagErrors(t *testing.T) {
+	fixtures := []struct {
+		tag string
+		err string
+	}{
+		{tag: "name  the name", err: "expected an option name at position 0"},
+		{tag: "", err: "expected an option name at position 0"},
+		{tag: "  -ab, --all  all", err: "short option name -ab must be a single character at position 2"},
+		{tag: "-a,  all", err: "expected a long option name after ',' at position 5"},
+		{tag: "-a, -all", err: "expected a long option name after ',' at position 4"},
+		{tag: "--  all", err: "invalid long option name -- at position 0"},
+		{tag: "---all", err: "invalid long option name ---all at position 0"},
+		{tag: "--a=b", err: "invalid long option name --a=b at position 0"},
+		{tag: "-a --all", err: "unexpected option name --all at position 3"},
+		{tag: "--port int env:  listen port", err: "missing value for attribute env at position 11"},
+		{tag: "--port envv:PORT  listen port", err: "unknown attribute envv at position 7"},
+		{tag: "--port group:'Network  listen port", err: "unterminated quoted value for attribute group at position 7"},
+		{tag: "--port group:''  listen port", err: "missing value for attribute group at position 7"},
 	}
 
 	for _, fixture := range fixtures {
-		flag, fieldType := parseStructFieldTag(fixture.tag)
-		require.Equal(t, fixture.short, flag.Short)
-		require.Equal(t, fixture.long, flag.Long)
-		require.Equal(t, fixture.fieldType, fieldType)
-		require.Equal(t, fixture.description, flag.Description)
+		_, err := parseStructFieldTag(fixture.tag)
+		require.EqualError(t, err, fixture.err, fixture.tag)
 	}
 }
 
+func TestParseStructTagAttributes(t *testing.T) {
+	var opts = struct {
+		Port   int      `flaq:"-p, --port default:8080 placeholder:number  listen port"`
+		Hosts  []string `flaq:"--host default:localhost  hosts"`
+		Secret string   `flaq:"--secret hidden  secret"`
+	}{}
+
+	flags := &FlagSet{}
+	flags.Struct(&opts)
+
+	require.Equal(t, 8080, opts.Port)
+	require.Equal(t, "8080", flags.Lookup("port").DefValue)
+	require.Contains(t, flags.Usage(), "-p, --port <number>")
+	require.NotContains(t, flags.Usage(), "--secret")
+
+	err := flags.Parse([]string{"--host=a", "--host=b"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, opts.Hosts)
+
+	require.PanicsWithValue(t, "invalid tag of struct field Port: short option name -pt must be a single character at position 0", func() {
+		var opts = struct {
+			Port int `flaq:"-pt, --port  listen port"`
+		}{}
+		(&FlagSet{}).Struct(&opts)
+	})
+	require.PanicsWithValue(t, "invalid tag of struct field Name: unknown type the at position 11", func() {
+		var opts = struct {
+			Name string `flaq:"-n, --name the name"`
+		}{}
+		(&FlagSet{}).Struct(&opts)
+	})
+	require.PanicsWithValue(t, `invalid default value "abc" of struct field Port: strconv.Atoi: parsing "abc": invalid syntax`, func() {
+		var opts = struct {
+			Port int `flaq:"--port default:abc  listen port"`
+		}{}
+		(&FlagSet{}).Struct(&opts)
+	})
+}
+
 func TestUsage(t *testing.T) {
 	var bar bool
 	var foo string
@@ -284,6 +746,42 @@ Options
 	require.Equal(t, expectedUsage, flags.Usage())
 }
 
+func TestUsageDefaults(t *testing.T) {
+	port, timeout := 8080, 5*time.Second
+	var name string
+
+	flags := &FlagSet{}
+	flags.Int(&port, "port", "p", "listen port")
+	flags.Duration(&timeout, "timeout", "", "request timeout")
+	flags.String(&name, "name", "", "server name")
+
+	expectedUsage := `Usage: flaq.test [options]
+
+Options
+      --name <string>        server name
+  -p, --port <int>           listen port (default 8080)
+      --timeout <duration>   request timeout (default 5s)
+`
+	require.Equal(t, expectedUsage, flags.Usage())
+}
+
+func TestUsageEnum(t *testing.T) {
+	var format string
+
+	flags := &FlagSet{}
+	flags.Enum(&format, "format", "f", "output format", "json", "yaml", "table")
+
+	expectedUsage := `Usage: flaq.test [options]
+
+Options
+  -f, --format <json|yaml|table>   output format
+`
+	require.Equal(t, expectedUsage, flags.Usage())
+
+	err := flags.Parse([]string{"--format=xml"})
+	require.EqualError(t, err, `invalid value "xml" for --format: allowed values are json, yaml, table`)
+}
+
 func TestCustomUsage(t *testing.T) {
 	customUsage := "This is custooooom"
 
@@ -295,3 +793,300 @@ func TestCustomUsage(t *testing.T) {
 
 	require.Equal(t, customUsage, flags.Usage())
 }
+
+func TestVisit(t *testing.T) {
+	var foo, bar bool
+	var count int
+	var name string
+
+	flags := &FlagSet{}
+	flags.Bool(&foo, "foo", "f", "", false)
+	flags.Bool(&bar, "bar", "b", "", false)
+	flags.Count(&count, "count", "c", "")
+	flags.String(&name, "name", "n", "")
+
+	err := flags.Parse([]string{"-cc", "--bar", "op", "--count"})
+	require.NoError(t, err)
+
+	var visited []string
+	flags.Visit(func(flag *Flag) {
+		visited = append(visited, flag.Long)
+	})
+	require.Equal(t, []string{"count", "bar"}, visited)
+
+	require.True(t, flags.Changed("count"))
+	require.True(t, flags.Changed("b"))
+	require.False(t, flags.Changed("foo"))
+	require.False(t, flags.Changed("unknown"))
+	require.Equal(t, 3, flags.Occurrences("c"))
+	require.Equal(t, 0, flags.Occurrences("name"))
+
+	err = flags.Parse([]string{"--foo", "op"})
+	require.NoError(t, err)
+	require.False(t, flags.Changed("count"))
+	require.True(t, flags.Changed("foo"))
+	require.Equal(t, []string{"op"}, flags.Args())
+}
+
+func TestLookup(t *testing.T) {
+	var name string
+
+	flags := &FlagSet{}
+	flags.String(&name, "name", "n", "")
+
+	require.Equal(t, "name", flags.Lookup("name").Long)
+	require.Equal(t, "name", flags.Lookup("n").Long)
+	require.Nil(t, flags.Lookup("unknown"))
+	require.Nil(t, flags.Lookup(""))
+}
+
+func TestAddInvalidFlag(t *testing.T) {
+	fixtures := []struct {
+		flag *Flag
+		err  string
+	}{
+		{
+			flag: &Flag{Value: new(boolValue)},
+			err:  "option without long nor short name",
+		},
+		{
+			flag: &Flag{Long: "--verbose", Value: new(boolValue)},
+			err:  `invalid option name "--verbose", names must not start with - nor contain = or spaces`,
+		},
+		{
+			flag: &Flag{Long: "log=level", Value: new(stringValue)},
+			err:  `invalid option name "log=level", names must not start with - nor contain = or spaces`,
+		},
+		{
+			flag: &Flag{Short: "vv", Value: new(boolValue)},
+			err:  `invalid short option name "vv", short names must be a single character other than - or =`,
+		},
+		{
+			flag: &Flag{Short: "-", Value: new(boolValue)},
+			err:  `invalid short option name "-", short names must be a single character other than - or =`,
+		},
+		{
+			flag: &Flag{Long: "debug"},
+			err:  "option --debug has no Value",
+		},
+		{
+			flag: &Flag{Long: "name", Value: new(stringValue)},
+			err:  "duplicate option --name",
+		},
+		{
+			flag: &Flag{Long: "nickname", Short: "n", Value: new(stringValue)},
+			err:  "duplicate option -n",
+		},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.err, func(t *testing.T) {
+			var name string
+
+			flags := &FlagSet{}
+			flags.String(&name, "name", "n", "")
+			require.PanicsWithValue(t, fixture.err, func() {
+				flags.Add(fixture.flag)
+			})
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var name, nickname string
+
+	flags := &FlagSet{}
+	flags.String(&name, "name", "n", "")
+	flags.String(&nickname, "nickname", "", "")
+	require.NoError(t, flags.Validate())
+
+	flags.Lookup("nickname").Short = "n"
+	require.EqualError(t, flags.Validate(), "duplicate option -n")
+}
+
+func TestParseHelpNameTaken(t *testing.T) {
+	var host string
+
+	flags := &FlagSet{}
+	flags.String(&host, "host", "h", "")
+
+	err := flags.Parse([]string{"-h", "localhost"})
+	require.NoError(t, err)
+	require.Equal(t, "localhost", host)
+	require.Equal(t, "help", flags.Lookup("help").Long)
+	require.Equal(t, "", flags.Lookup("help").Short)
+	require.NoError(t, flags.Validate())
+}
+
+// newHelpFlagSets returns two independent FlagSets, none of them being the
+// package-level one.
+func newHelpFlagSets() (*FlagSet, *FlagSet) {
+	var name string
+	var port int
+
+	a, b := &FlagSet{}, &FlagSet{}
+	a.String(&name, "name", "n", "name of the person to greet")
+	b.Int(&port, "port", "p", "listen port")
+	b.Help("usage", "u", "show this message")
+	return a, b
+}
+
+func TestHelpFlagSetScope(t *testing.T) {
+	a, b := newHelpFlagSets()
+	require.NoError(t, a.Parse([]string{}))
+	require.NoError(t, b.Parse([]string{}))
+
+	require.NotNil(t, a.Lookup("help"))
+	require.NotNil(t, a.Lookup("h"))
+	require.Nil(t, a.Lookup("usage"))
+	require.NotNil(t, b.Lookup("usage"))
+	require.Nil(t, b.Lookup("help"))
+	require.Nil(t, flags.Lookup("help"))
+	require.Nil(t, flags.Lookup("usage"))
+}
+
+func TestParseHelp(t *testing.T) {
+	fixtures := []struct {
+		set   string
+		usage string
+	}{
+		{
+			set: "a",
+			usage: `Usage: flaq.test [options]
+
+Options
+  -h, --help            show usage help
+  -n, --name <string>   name of the person to greet
+`,
+		},
+		{
+			set: "b",
+			usage: `Usage: flaq.test [options]
+
+Options
+  -p, --port <int>   listen port
+  -u, --usage        show this message
+`,
+		},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.set, func(t *testing.T) {
+			var out bytes.Buffer
+
+			a, b := newHelpFlagSets()
+			a.Output, b.Output = &out, &out
+			if fixture.set == "a" {
+				require.Equal(t, ErrHelp, a.Parse([]string{"-h"}))
+			} else {
+				require.Equal(t, ErrHelp, b.Parse([]string{"--usage"}))
+			}
+			require.Equal(t, fixture.usage, out.String())
+		})
+	}
+}
+
+func TestParseHelpExitOnError(t *testing.T) {
+	var out bytes.Buffer
+	var code = -1
+
+	a, _ := newHelpFlagSets()
+	a.ErrorHandling, a.Output, a.Exit = ExitOnError, &out, func(c int) { code = c }
+
+	require.Equal(t, ErrHelp, a.Parse([]string{"--help"}))
+	require.Equal(t, 0, code)
+	require.Contains(t, out.String(), "--name <string>")
+
+	require.NoError(t, a.Parse([]string{"--name", "joe"}))
+}
+
+func TestParseErrorExitOnError(t *testing.T) {
+	var out bytes.Buffer
+	var code = -1
+
+	a, _ := newHelpFlagSets()
+	a.ErrorHandling, a.ErrOutput, a.Exit = ExitOnError, &out, func(c int) { code = c }
+
+	require.Error(t, a.Parse([]string{"--nme"}))
+	require.Equal(t, 2, code)
+	require.Equal(t, "unknown option --nme, did you mean --name?\n", out.String())
+}
+
+func TestParseVersion(t *testing.T) {
+	var out bytes.Buffer
+	var name string
+
+	flags := &FlagSet{Output: &out}
+	flags.String(&name, "name", "n", "")
+	flags.Version("version", "V", "greet 1.2.3")
+
+	require.Equal(t, ErrVersion, flags.Parse([]string{"--version", "--name"}))
+	require.Equal(t, "greet 1.2.3\n", out.String())
+
+	out.Reset()
+	require.NoError(t, flags.Parse([]string{"--name", "joe"}))
+	require.Equal(t, "", out.String())
+
+	var code = -1
+	flags.ErrorHandling, flags.Exit = ExitOnError, func(c int) { code = c }
+	flags.Version("version", "V", "")
+	require.Equal(t, ErrVersion, flags.Parse([]string{"-V"}))
+	require.Equal(t, 0, code)
+	require.True(t, strings.HasPrefix(out.String(), "flaq.test "))
+	require.Contains(t, flags.Usage(), "-V, --version")
+}
+
+func TestUsageGroups(t *testing.T) {
+	var opts = struct {
+		Verbose bool   `flaq:"-v, --verbose  verbose output"`
+		Port    int    `flaq:"-p, --port group:Network  listen port"`
+		Host    string `flaq:"    --host group:Network  listen address"`
+		Level   string `flaq:"    --log-level group:Logging  log level"`
+		Debug   bool   `flaq:"    --debug group:'Advanced Options'  debug mode"`
+		Color   bool   `flaq:"    --color  colored output"`
+	}{}
+
+	flags := &FlagSet{DisableHelp: true}
+	flags.Struct(&opts)
+
+	expectedUsage := `Usage: flaq.test [options]
+
+Options
+      --color                colored output
+  -v, --verbose              verbose output
+
+Network
+      --host <string>        listen address
+  -p, --port <int>           listen port
+
+Logging
+      --log-level <string>   log level
+
+Advanced Options
+      --debug                debug mode
+`
+	require.Equal(t, expectedUsage, flags.Usage())
+
+	flags.KeepOrder = true
+	expectedUsage = `Usage: flaq.test [options]
+
+Options
+  -v, --verbose              verbose output
+      --color                colored output
+
+Network
+  -p, --port <int>           listen port
+      --host <string>        listen address
+
+Logging
+      --log-level <string>   log level
+
+Advanced Options
+      --debug                debug mode
+`
+	require.Equal(t, expectedUsage, flags.Usage())
+
+	grouped := &FlagSet{}
+	grouped.Add(&Flag{Long: "port", Value: new(intValue), Arg: &FlagArg{}, Group: "Network"})
+	require.NotContains(t, grouped.Usage(), "Options")
+}
@@ -0,0 +1,156 @@
+package flaq
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Man writes a man page in roff format, with NAME, SYNOPSIS, OPTIONS and
+// ENVIRONMENT sections. section is the manual section (eg. 1 for user
+// commands), and description is a one-line description of the program.
+func Man(w io.Writer, section int, description string) error {
+	return flags.Man(w, section, description)
+}
+
+// Markdown writes a Markdown reference, with synopsis, options and environment
+// variables. description is a one-line description of the program.
+func Markdown(w io.Writer, description string) error {
+	return flags.Markdown(w, description)
+}
+
+// Man writes a man page in roff format, with NAME, SYNOPSIS, OPTIONS and
+// ENVIRONMENT sections. section is the manual section (eg. 1 for user
+// commands), and description is a one-line description of the program.
+func (f *FlagSet) Man(w io.Writer, section int, description string) error {
+	name := f.program()
+	if f.command != nil {
+		name = f.command.Path()
+	}
+
+	page := fmt.Sprintf(".TH \"%s\" \"%d\"\n", strings.ToUpper(strings.Replace(name, " ", "-", -1)), section)
+	page += ".SH NAME\n"
+	page += roffEscape(name)
+	if description != "" {
+		page += " \\- " + roffEscape(description)
+	}
+	page += "\n.SH SYNOPSIS\n" + roffEscape(synopsis(f)) + "\n"
+
+	page += ".SH OPTIONS\n"
+	for _, group := range flagGroups(f) {
+		if group != "" {
+			page += ".SS " + roffEscape(group) + "\n"
+		}
+		for _, flag := range visibleFlags(f) {
+			if flag.Group == group {
+				page += ".TP\n\\fB" + roffEscape(strings.TrimSpace(flagUsage(flag))) + "\\fR\n"
+				page += roffEscape(flagDescription(f, flag)) + "\n"
+			}
+		}
+	}
+
+	if f.command != nil && len(f.command.commands) > 0 {
+		page += ".SH COMMANDS\n"
+		for _, cmd := range f.command.commands {
+			page += ".TP\n\\fB" + roffEscape(cmd.Name) + "\\fR\n" + roffEscape(cmd.Description) + "\n"
+		}
+	}
+
+	env := ""
+	for _, flag := range visibleFlags(f) {
+		if name := f.envName(flag); name != "" {
+			env += ".TP\n\\fB" + roffEscape(name) + "\\fR\n"
+			env += "Sets " + roffEscape(flagName(flag.Long, flag.Short)) + ".\n"
+		}
+	}
+	if env != "" {
+		page += ".SH ENVIRONMENT\n" + env
+	}
+
+	_, err := io.WriteString(w, page)
+	return err
+}
+
+// Markdown writes a Markdown reference, with synopsis, options and environment
+// variables. description is a one-line description of the program.
+func (f *FlagSet) Markdown(w io.Writer, description string) error {
+	name := f.program()
+	if f.command != nil {
+		name = f.command.Path()
+	}
+
+	doc := "# " + name + "\n\n"
+	if description != "" {
+		doc += description + "\n\n"
+	}
+	doc += "## Synopsis\n\n```\n" + synopsis(f) + "\n```\n\n"
+
+	doc += "## Options\n"
+	for _, group := range flagGroups(f) {
+		if group != "" {
+			doc += "\n### " + group + "\n"
+		}
+		doc += "\n| Option | Description |\n| --- | --- |\n"
+		for _, flag := range visibleFlags(f) {
+			if flag.Group == group {
+				doc += "| `" + markdownEscape(strings.TrimSpace(flagUsage(flag))) + "` | " + markdownEscape(flagDescription(f, flag)) + " |\n"
+			}
+		}
+	}
+
+	if f.command != nil && len(f.command.commands) > 0 {
+		doc += "\n## Commands\n\n| Command | Description |\n| --- | --- |\n"
+		for _, cmd := range f.command.commands {
+			doc += "| `" + cmd.Name + "` | " + markdownEscape(cmd.Description) + " |\n"
+		}
+	}
+
+	env := ""
+	for _, flag := range visibleFlags(f) {
+		if name := f.envName(flag); name != "" {
+			env += "| `" + name + "` | `" + flagName(flag.Long, flag.Short) + "` |\n"
+		}
+	}
+	if env != "" {
+		doc += "\n## Environment\n\n| Variable | Option |\n| --- | --- |\n" + env
+	}
+
+	_, err := io.WriteString(w, doc)
+	return err
+}
+
+// Man writes a man page in roff format, using the command description.
+func (c *Command) Man(w io.Writer, section int) error {
+	c.Flags.command = c
+	return c.Flags.Man(w, section, c.Description)
+}
+
+// Markdown writes a Markdown reference, using the command description.
+func (c *Command) Markdown(w io.Writer) error {
+	c.Flags.command = c
+	return c.Flags.Markdown(w, c.Description)
+}
+
+// synopsis returns the usage line without its "Usage:" prefix.
+func synopsis(f *FlagSet) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(usageLine(f)), "Usage:"))
+}
+
+// roffEscape escapes text for roff: backslashes and dashes are escaped,
+// and lines starting with a control character are protected.
+func roffEscape(s string) string {
+	s = strings.Replace(s, `\`, `\e`, -1)
+	s = strings.Replace(s, "-", `\-`, -1)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markdownEscape escapes pipes, which would otherwise break Markdown tables.
+func markdownEscape(s string) string {
+	return strings.Replace(s, "|", `\|`, -1)
+}
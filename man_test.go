@@ -0,0 +1,62 @@
+package flaq
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func manCommand() *Command {
+	name, format := "world", "text"
+	var yell bool
+	var port int
+
+	cmd := &Command{Name: "greet", Description: "greet a person"}
+	cmd.Flags.String(&name, "name", "n", "name of the person to greet")
+	cmd.Flags.Enum(&format, "format", "f", "output format", "text", "json")
+	cmd.Flags.Bool(&yell, "yell", "", "greet the person loudly", false)
+	cmd.Flags.Add(&Flag{
+		Long:        "port",
+		Description: "listen port",
+		Value:       (*intValue)(&port),
+		Arg:         &FlagArg{},
+		Env:         "GREET_PORT",
+		Group:       "Network",
+	})
+	cmd.Flags.Add(&Flag{Long: "secret", Value: new(boolValue), Hidden: true})
+	return cmd
+}
+
+func TestMan(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, manCommand().Man(&buf, 1))
+
+	golden := "testdata/greet.1"
+	if *update {
+		require.NoError(t, ioutil.WriteFile(golden, buf.Bytes(), 0644))
+	}
+	expected, err := ioutil.ReadFile(golden)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), buf.String())
+}
+
+func TestMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, manCommand().Markdown(&buf))
+
+	golden := "testdata/greet.md"
+	if *update {
+		require.NoError(t, ioutil.WriteFile(golden, buf.Bytes(), 0644))
+	}
+	expected, err := ioutil.ReadFile(golden)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), buf.String())
+}
+
+func TestRoffEscape(t *testing.T) {
+	require.Equal(t, `\-\-name`, roffEscape("--name"))
+	require.Equal(t, `a \e b`, roffEscape(`a \ b`))
+	require.Equal(t, "\\&.TH\n\\&'quote", roffEscape(".TH\n'quote"))
+}
@@ -0,0 +1,244 @@
+package flaq
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// structFieldTypes holds the struct field types registered with RegisterType.
+var structFieldTypes = map[string]func(field interface{}) Value{}
+
+// RegisterType registers a type name usable in struct field tags. newValue is
+// given a pointer to the struct field, and returns the Value used to set it.
+func RegisterType(name string, newValue func(field interface{}) Value) {
+	structFieldTypes[name] = newValue
+}
+
+// structFlags adds the flags defined by the fields of a struct. Long names
+// are prefixed with prefix, and flags are listed in the group help section.
+func (f *FlagSet) structFlags(sval reflect.Value, prefix, group string) {
+	for i := 0; i < sval.NumField(); i++ {
+		field, structField := sval.Field(i), sval.Type().Field(i)
+		tag, ok := structField.Tag.Lookup("flaq")
+		if isNestedStruct(field, structField, tag) {
+			nestedPrefix, nestedGroup := parseNestedStructTag(structField, tag)
+			if nestedGroup == "" {
+				nestedGroup = group
+			}
+			f.structFlags(field, prefix+nestedPrefix, nestedGroup)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		t, err := parseStructFieldTag(tag)
+		if err != nil {
+			panic(fmt.Sprintf("invalid tag of struct field %s: %v", structField.Name, err))
+		}
+		flag, newValue := t.flag, func() (Value, *FlagArg) {
+			if t.fieldType == "" && field.Kind() != reflect.Bool {
+				return inferStructFieldValue(structField.Name, field)
+			}
+			return structFieldValue(structField.Name, t.fieldType, field)
+		}
+		flag.Value, flag.Arg = newValue()
+		if flag.Value == nil {
+			panic(fmt.Sprintf("invalid tag of struct field %s: unknown type %s at position %d", structField.Name, t.fieldType, t.typePos))
+		}
+		if t.hasDefault {
+			// The default value is set on the field itself, the flag value
+			// being recreated for the default to be its initial value.
+			if err := flag.Value.Set(t.defValue); err != nil {
+				panic(fmt.Sprintf("invalid default value %q of struct field %s: %v", t.defValue, structField.Name, err))
+			}
+			flag.Value, flag.Arg = newValue()
+		}
+		if t.placeholder != "" {
+			if flag.Arg == nil {
+				panic(fmt.Sprintf("invalid tag of struct field %s: placeholder set on an option without argument", structField.Name))
+			}
+			flag.Arg.Name = t.placeholder
+		}
+		if flag.Long != "" {
+			flag.Long = prefix + flag.Long
+		}
+		if flag.Group == "" {
+			flag.Group = group
+		}
+		f.Add(flag)
+	}
+}
+
+// isNestedStruct reports whether a struct field holds a struct whose fields
+// define flags, rather than defining a flag itself (eg. a time.Time field).
+func isNestedStruct(field reflect.Value, structField reflect.StructField, tag string) bool {
+	if field.Kind() != reflect.Struct || structField.PkgPath != "" {
+		return false
+	}
+	return !strings.HasPrefix(strings.TrimSpace(tag), "-") && interfaceValue(field, "") == nil
+}
+
+// parseNestedStructTag returns the long name prefix and the help usage section
+// of the flags defined by a nested struct. The tag is made of an optional
+// prefix:NAME attribute, followed by the section title. The prefix defaults
+// to the field name for named fields, and to no prefix for embedded structs.
+// The section title defaults to the field name for named fields.
+func parseNestedStructTag(structField reflect.StructField, tag string) (string, string) {
+	prefix, group := "", strings.TrimSpace(tag)
+	hasPrefix := strings.HasPrefix(group, "prefix:")
+	if hasPrefix {
+		attr := group
+		group = ""
+		if i := strings.IndexByte(attr, ' '); i >= 0 {
+			attr, group = attr[:i], strings.TrimSpace(attr[i+1:])
+		}
+		prefix = strings.TrimPrefix(attr, "prefix:")
+	}
+	if !structField.Anonymous {
+		if !hasPrefix {
+			prefix = kebabCase(structField.Name)
+		}
+		if group == "" {
+			group = structField.Name
+		}
+	}
+	if prefix != "" {
+		prefix += "-"
+	}
+	return prefix, group
+}
+
+// kebabCase converts a Go identifier such as DBOptions to kebab case (db-options).
+func kebabCase(name string) string {
+	var s []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				s = append(s, '-')
+			}
+			r = unicode.ToLower(r)
+		}
+		s = append(s, r)
+	}
+	return string(s)
+}
+
+// structFieldValue returns the value and argument of the flag defined by a struct
+// field, given the type from its tag, or a nil value if the type is unknown. It
+// panics, naming the field, when the type does not match the Go type of the field.
+func structFieldValue(name, fieldType string, field reflect.Value) (Value, *FlagArg) {
+	mismatch := fmt.Sprintf("struct field %s of type %s cannot hold a flag of type %s", name, field.Type(), fieldType)
+	ptr := func(typ interface{}) interface{} {
+		t := reflect.TypeOf(typ)
+		if !field.Addr().Type().ConvertibleTo(t) {
+			panic(mismatch)
+		}
+		return field.Addr().Convert(t).Interface()
+	}
+
+	switch fieldType {
+	case "count":
+		return (*countValue)(ptr((*int)(nil)).(*int)), nil
+	case "duration":
+		return (*durationValue)(ptr((*time.Duration)(nil)).(*time.Duration)), &FlagArg{Name: "duration"}
+	case "float64":
+		return (*float64Value)(ptr((*float64)(nil)).(*float64)), &FlagArg{Name: "float"}
+	case "int":
+		return (*intValue)(ptr((*int)(nil)).(*int)), &FlagArg{Name: "int"}
+	case "string":
+		return (*stringValue)(ptr((*string)(nil)).(*string)), &FlagArg{Name: "string"}
+	case "bool":
+		return (*boolValue)(ptr((*bool)(nil)).(*bool)), &FlagArg{Default: "true", Name: "bool"}
+	case "":
+		return (*boolValue)(ptr((*bool)(nil)).(*bool)), nil
+	case "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32":
+		if field.Kind().String() != fieldType {
+			panic(mismatch)
+		}
+		argName := "int"
+		if strings.HasPrefix(fieldType, "uint") {
+			argName = "uint"
+		} else if fieldType == "float32" {
+			argName = "float"
+		}
+		return &numberValue{value: field}, &FlagArg{Name: argName}
+	case "[]string":
+		return &stringSliceValue{value: ptr((*[]string)(nil)).(*[]string)}, &FlagArg{Name: "string"}
+	case "[]int":
+		return &intSliceValue{value: ptr((*[]int)(nil)).(*[]int)}, &FlagArg{Name: "int"}
+	case "[]float64":
+		return &float64SliceValue{value: ptr((*[]float64)(nil)).(*[]float64)}, &FlagArg{Name: "float"}
+	case "[]duration":
+		return &durationSliceValue{value: ptr((*[]time.Duration)(nil)).(*[]time.Duration)}, &FlagArg{Name: "duration"}
+	case "map[string]string":
+		return &stringMapValue{value: ptr((*map[string]string)(nil)).(*map[string]string)}, &FlagArg{Name: "key=value"}
+	}
+
+	if newValue, ok := structFieldTypes[fieldType]; ok {
+		return newValue(field.Addr().Interface()), &FlagArg{Name: fieldType}
+	}
+	if value := interfaceValue(field, fieldType); value != nil {
+		return value, &FlagArg{Name: fieldType}
+	}
+	if !strings.Contains(fieldType, "|") {
+		return nil, nil
+	}
+	// A type such as json|yaml|table restricts values to a set of choices.
+	return &enumValue{value: ptr((*string)(nil)).(*string), choices: strings.Split(fieldType, "|")}, &FlagArg{Name: fieldType}
+}
+
+// inferStructFieldValue returns the value and argument of the flag defined by a
+// struct field whose tag has no type, which is then inferred from the Go type.
+func inferStructFieldValue(name string, field reflect.Value) (Value, *FlagArg) {
+	typeName := strings.ToLower(field.Type().Name())
+	if value := interfaceValue(field, typeName); value != nil {
+		return value, &FlagArg{Name: typeName}
+	}
+	return structFieldValue(name, fieldTypeOf(name, field.Type()), field)
+}
+
+// interfaceValue returns the Value of a struct field implementing Value or
+// encoding.TextUnmarshaler, or nil if it implements neither.
+func interfaceValue(field reflect.Value, typeName string) Value {
+	switch v := field.Addr().Interface().(type) {
+	case Value:
+		return v
+	case encoding.TextUnmarshaler, encoding.BinaryUnmarshaler:
+		return &textValue{value: v, typ: typeName}
+	}
+	return nil
+}
+
+// fieldTypeOf returns the struct field type matching a Go type.
+func fieldTypeOf(name string, t reflect.Type) string {
+	if t == durationType {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return ""
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return t.Kind().String()
+	case reflect.Slice:
+		if t.Elem() == durationType {
+			return "[]duration"
+		}
+		switch t.Elem().Kind() {
+		case reflect.String, reflect.Int, reflect.Float64:
+			return "[]" + t.Elem().Kind().String()
+		}
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String {
+			return "map[string]string"
+		}
+	}
+	panic(fmt.Sprintf("cannot infer the flag type of struct field %s of type %s", name, t))
+}
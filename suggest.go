@@ -0,0 +1,85 @@
+package flaq
+
+import "strings"
+
+// suggest returns the names of the visible flags closest to an unknown long
+// option name, as they appear on the command line. A short option is only
+// suggested when its name matches exactly (eg. -v for --v).
+func (f *FlagSet) suggest(name string) []string {
+	var candidates []string
+	for _, flag := range f.flags {
+		if flag.Hidden {
+			continue
+		}
+		if flag.Long != "" {
+			candidates = append(candidates, "--"+flag.Long)
+		}
+		if flag.Short != "" && flag.Short == name {
+			candidates = append(candidates, "-"+flag.Short)
+		}
+	}
+	return closest(name, candidates)
+}
+
+// suggest returns the names of the subcommands closest to an unknown command name.
+func (c *Command) suggest(name string) []string {
+	candidates := make([]string, len(c.commands))
+	for i, cmd := range c.commands {
+		candidates[i] = cmd.Name
+	}
+	return closest(name, candidates)
+}
+
+// closest returns the candidates closest to name, leading dashes of candidates
+// being ignored. Closeness is measured by edit distance, and candidates starting
+// with name are considered close as well. It returns nothing when no candidate
+// is close enough.
+func closest(name string, candidates []string) []string {
+	var suggestions []string
+	best := len(name)/3 + 1
+
+	for _, candidate := range candidates {
+		trimmed := strings.TrimLeft(candidate, "-")
+		d := distance(name, trimmed)
+		if strings.HasPrefix(trimmed, name) && d > 1 {
+			// An abbreviation is as close as a typo.
+			d = 1
+		}
+		switch {
+		case d < best:
+			suggestions, best = []string{candidate}, d
+		case d == best:
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions
+}
+
+// distance returns the Levenshtein distance between two strings, that is the
+// minimum number of single character insertions, deletions or substitutions
+// required to change one into the other.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,26 @@
+package flaq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistance(t *testing.T) {
+	fixtures := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"verbose", "verbose", 0},
+		{"verbos", "verbose", 1},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"vrebose", "verbose", 2},
+	}
+
+	for _, fixture := range fixtures {
+		require.Equal(t, fixture.distance, distance(fixture.a, fixture.b))
+		require.Equal(t, fixture.distance, distance(fixture.b, fixture.a))
+	}
+}
@@ -0,0 +1,128 @@
+package flaq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// structTag is a parsed struct field tag.
+type structTag struct {
+	flag        *Flag
+	fieldType   string
+	typePos     int
+	defValue    string
+	hasDefault  bool
+	placeholder string
+}
+
+// parseStructFieldTag parses a struct field tag, which follows the grammar:
+//
+//	tag         = names [" " type] {" " attribute} [separator description]
+//	names       = "-" short [", " "--" long] | "--" long
+//	attribute   = "required" | "hidden" | key ":" value
+//	key         = "default" | "env" | "group" | "placeholder"
+//	value       = word | "'" {character} "'"
+//	separator   = " " " " {" "}
+//
+// Leading spaces are ignored, for tags to be aligned. The description may also
+// follow a single space after the type or an attribute. Without either, the first
+// word of such a description is read as the type, which is then reported as
+// unknown. Errors report the position in the tag where parsing failed.
+func parseStructFieldTag(tag string) (*structTag, error) {
+	t := &structTag{flag: &Flag{}}
+
+	p := len(tag) - len(strings.TrimLeft(tag, " "))
+	if p == len(tag) || tag[p] != '-' {
+		return nil, fmt.Errorf("expected an option name at position %d", p)
+	}
+
+	if !strings.HasPrefix(tag[p:], "--") {
+		name := tagWord(tag[p+1:], " ,")
+		if len(name) != 1 {
+			return nil, fmt.Errorf("short option name -%s must be a single character at position %d", name, p)
+		}
+		t.flag.Short, p = name, p+2
+		if p < len(tag) && tag[p] == ',' {
+			p++
+			for p < len(tag) && tag[p] == ' ' {
+				p++
+			}
+			if !strings.HasPrefix(tag[p:], "--") {
+				return nil, fmt.Errorf("expected a long option name after ',' at position %d", p)
+			}
+		}
+	}
+
+	if strings.HasPrefix(tag[p:], "--") {
+		name := tagWord(tag[p+2:], " ")
+		if name == "" || name[0] == '-' || strings.ContainsAny(name, "=,") {
+			return nil, fmt.Errorf("invalid long option name --%s at position %d", name, p)
+		}
+		t.flag.Long, p = name, p+2+len(name)
+	}
+
+	expectType := true
+	for p < len(tag) {
+		spaces := len(tag[p:]) - len(strings.TrimLeft(tag[p:], " "))
+		if spaces != 1 {
+			t.flag.Description = strings.TrimSpace(tag[p:])
+			break
+		}
+		p++
+
+		word := tagWord(tag[p:], " ")
+		key, value := word, ""
+		if i := strings.IndexByte(word, ':'); i >= 0 {
+			key, value = word[:i], word[i+1:]
+			if strings.HasPrefix(value, "'") {
+				// A quoted value may contain spaces (eg. group:'Advanced Options').
+				end := strings.IndexByte(tag[p+i+2:], '\'')
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated quoted value for attribute %s at position %d", key, p)
+				}
+				word, value = tag[p:p+i+3+end], tag[p+i+2:p+i+2+end]
+			}
+		}
+
+		switch {
+		case word == "required":
+			t.flag.Required = true
+		case word == "hidden":
+			t.flag.Hidden = true
+		case key == "default" || key == "env" || key == "group" || key == "placeholder":
+			if value == "" {
+				return nil, fmt.Errorf("missing value for attribute %s at position %d", key, p)
+			}
+			switch key {
+			case "default":
+				t.defValue, t.hasDefault = value, true
+			case "env":
+				t.flag.Env = value
+			case "group":
+				t.flag.Group = value
+			case "placeholder":
+				t.placeholder = value
+			}
+		case expectType && strings.HasPrefix(word, "-"):
+			return nil, fmt.Errorf("unexpected option name %s at position %d", word, p)
+		case expectType && strings.Contains(word, ":"):
+			return nil, fmt.Errorf("unknown attribute %s at position %d", key, p)
+		case expectType:
+			t.fieldType, t.typePos = word, p
+		default:
+			t.flag.Description = strings.TrimSpace(tag[p:])
+			return t, nil
+		}
+		expectType = false
+		p += len(word)
+	}
+	return t, nil
+}
+
+// tagWord returns the beginning of s, up to the first of the given separators.
+func tagWord(s, separators string) string {
+	if i := strings.IndexAny(s, separators); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
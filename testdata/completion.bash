@@ -0,0 +1,28 @@
+# bash completion for greet-cli
+
+_greet_cli_complete() {
+    local line="${COMP_LINE:0:COMP_POINT}" words out cur directive
+    read -ra words <<< "$line"
+    if [[ -z $line || $line == *[[:space:]] ]]; then
+        words+=("")
+    fi
+    cur="${words[${#words[@]}-1]}"
+
+    local IFS=$'\n'
+    out=($("${words[0]}" __complete "${words[@]:1}" 2>/dev/null))
+    directive=0
+    if [[ ${#out[@]} -gt 0 && ${out[${#out[@]}-1]} == :* ]]; then
+        directive="${out[${#out[@]}-1]#:}"
+        unset 'out[${#out[@]}-1]'
+    fi
+
+    if [[ $cur == *=* && $COMP_WORDBREAKS == *=* ]]; then
+        out=("${out[@]#"${cur%%=*}="}")
+    fi
+    COMPREPLY=("${out[@]}")
+    if (( directive & 1 )); then
+        compopt -o default
+    fi
+}
+
+complete -F _greet_cli_complete greet-cli
@@ -0,0 +1,20 @@
+# fish completion for greet-cli
+
+function __greet_cli_complete
+    set -l cur (commandline -ct)
+    set -l words (commandline -opc) "$cur"
+    set -l out ($words[1] __complete $words[2..-1] 2>/dev/null)
+    set -l directive 0
+    if test (count $out) -gt 0; and string match -qr '^:' -- $out[-1]
+        set directive (string sub -s 2 -- $out[-1])
+        set -e out[-1]
+    end
+
+    if test (math "$directive % 2") -eq 1; and test (count $out) -eq 0
+        __fish_complete_path $cur
+        return
+    end
+    printf '%s\n' $out
+end
+
+complete -c greet-cli -f -a '(__greet_cli_complete)'
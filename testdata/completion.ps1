@@ -0,0 +1,27 @@
+# powershell completion for greet-cli
+
+Register-ArgumentCompleter -Native -CommandName 'greet-cli' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $words = @($commandAst.CommandElements |
+        Where-Object { $_.Extent.StartOffset -lt $cursorPosition } |
+        ForEach-Object { $_.Extent.Text })
+    if ($wordToComplete -eq '') {
+        $words += ''
+    }
+    $program, $arguments = $words
+
+    $out = @(& $program __complete @arguments 2>$null)
+    $directive = 0
+    if ($out.Count -gt 0 -and $out[-1] -like ':*') {
+        $directive = [int]$out[-1].Substring(1)
+        $out = @($out | Select-Object -SkipLast 1)
+    }
+
+    if (($directive -band 1) -and $out.Count -eq 0) {
+        return
+    }
+    $out | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
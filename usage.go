@@ -2,29 +2,72 @@ package flaq
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"strconv"
 )
 
 func defaultUsage(flags *FlagSet) string {
-	usage := flags.UsageLine
-	if usage == "" {
-		usage = "Usage: " + filepath.Base(os.Args[0]) + " [options]\n"
-	}
-	usage += "\nOptions\n"
+	usage := usageLine(flags)
 
 	usages, maxUsageLen := make(map[*Flag]string), 0
+	sortedFlags := visibleFlags(flags)
+
+	for _, f := range sortedFlags {
+		usages[f] = flagUsage(f)
+		if len(usages[f]) > maxUsageLen {
+			maxUsageLen = len(usages[f])
+		}
+	}
+	if maxUsageLen > 25 {
+		maxUsageLen = 25
+	}
+	for _, group := range flagGroups(flags) {
+		if group == "" {
+			usage += "\nOptions\n"
+		} else {
+			usage += "\n" + group + "\n"
+		}
+		for _, f := range sortedFlags {
+			if f.Group == group {
+				usage += fmt.Sprintf("  %-"+strconv.Itoa(maxUsageLen)+"s   %s\n", usages[f], flagDescription(flags, f))
+			}
+		}
+	}
+	if len(flags.constraints) > 0 {
+		usage += "\nConstraints\n"
+		for _, c := range flags.constraints {
+			usage += "  " + c.usage(flags) + "\n"
+		}
+	}
+	if flags.command != nil && len(flags.command.commands) > 0 {
+		usage += commandsUsage(flags.command)
+	}
+	return usage
+}
+
+// usageLine returns the first line of the help usage.
+func usageLine(flags *FlagSet) string {
+	if flags.UsageLine != "" {
+		return flags.UsageLine
+	}
+	if flags.command == nil {
+		return "Usage: " + flags.program() + " [options]\n"
+	}
+	usage := "Usage: " + flags.command.Path() + " [options]"
+	if len(flags.command.commands) > 0 {
+		usage += " <command>"
+	}
+	return usage + "\n"
+}
+
+// visibleFlags returns the flags that are not hidden, sorted by name unless
+// the FlagSet keeps their registration order.
+func visibleFlags(flags *FlagSet) []*Flag {
 	sortedFlags := make([]*Flag, 0, len(flags.flags))
 
 	flags.VisitAll(func(f *Flag) {
 		if !f.Hidden {
-			usages[f] = flagUsage(f)
-			if len(usages[f]) > maxUsageLen {
-				maxUsageLen = len(usages[f])
-			}
 			for i := range sortedFlags {
-				if sortedFlags[i].Long+sortedFlags[i].Short > f.Long+f.Short {
+				if !flags.KeepOrder && sortedFlags[i].Long+sortedFlags[i].Short > f.Long+f.Short {
 					sortedFlags = append(sortedFlags, nil)
 					copy(sortedFlags[i+1:], sortedFlags[i:])
 					sortedFlags[i] = f
@@ -34,15 +77,70 @@ func defaultUsage(flags *FlagSet) string {
 			sortedFlags = append(sortedFlags, f)
 		}
 	})
-	if maxUsageLen > 25 {
-		maxUsageLen = 25
+	return sortedFlags
+}
+
+// flagGroups returns the help usage sections of the visible flags: the default
+// "Options" section, followed by flag groups in registration order. The default
+// section is omitted when all flags belong to a group.
+func flagGroups(flags *FlagSet) []string {
+	var groups []string
+	ungrouped, seen := false, make(map[string]bool)
+	flags.VisitAll(func(f *Flag) {
+		switch {
+		case f.Hidden:
+		case f.Group == "":
+			ungrouped = true
+		case !seen[f.Group]:
+			groups, seen[f.Group] = append(groups, f.Group), true
+		}
+	})
+	if ungrouped || len(groups) == 0 {
+		groups = append([]string{""}, groups...)
 	}
-	for _, f := range sortedFlags {
-		usage += fmt.Sprintf("  %-"+strconv.Itoa(maxUsageLen)+"s   %s\n", usages[f], f.Description)
+	return groups
+}
+
+// flagDescription returns the flag description, along with its default
+// value, environment variable and whether it is required.
+func flagDescription(flags *FlagSet, f *Flag) string {
+	description := f.Description
+	if !isZeroValue(f.DefValue) {
+		description += " (default " + f.DefValue + ")"
+	}
+	if env := flags.envName(f); env != "" {
+		description += " [$" + env + "]"
+	}
+	if f.Required {
+		description += " (required)"
+	}
+	return description
+}
+
+// commandsUsage returns the help usage section listing subcommands.
+func commandsUsage(c *Command) string {
+	usage, maxNameLen := "\nCommands\n", 0
+	for _, cmd := range c.commands {
+		if len(cmd.Name) > maxNameLen {
+			maxNameLen = len(cmd.Name)
+		}
+	}
+	for _, cmd := range c.commands {
+		usage += fmt.Sprintf("  %-"+strconv.Itoa(maxNameLen)+"s   %s\n", cmd.Name, cmd.Description)
 	}
 	return usage
 }
 
+// isZeroValue reports whether a default value is the zero value of its type,
+// in which case it is not shown in help usage.
+func isZeroValue(val string) bool {
+	switch val {
+	case "", "0", "false", "0s", "[]":
+		return true
+	}
+	return false
+}
+
 // flagUsage returns the help usage for a given flag.
 func flagUsage(f *Flag) string {
 	if f.Usage != "" {
@@ -62,6 +160,9 @@ func flagUsage(f *Flag) string {
 
 	if f.Arg != nil {
 		argName := f.Arg.Name
+		if v, ok := f.Value.(TypedValue); ok && argName == "" {
+			argName = v.Type()
+		}
 		if argName == "" {
 			argName = "arg"
 		}
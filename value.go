@@ -1,7 +1,13 @@
 package flaq
 
 import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -10,6 +16,20 @@ type Value interface {
 	Set(string) error
 }
 
+// TypedValue is an optional interface for values able to describe themselves.
+// All built-in values implement it. When a flag value implements TypedValue,
+// its initial value is recorded as the flag default value, and its type name
+// is used as argument name in help usage, unless one is set.
+type TypedValue interface {
+	Value
+
+	// String returns the current value as text.
+	String() string
+
+	// Type returns the type name of the value (eg. "int").
+	Type() string
+}
+
 type stringValue string
 
 func (s *stringValue) Set(val string) error {
@@ -17,6 +37,14 @@ func (s *stringValue) Set(val string) error {
 	return nil
 }
 
+func (s *stringValue) String() string {
+	return string(*s)
+}
+
+func (s *stringValue) Type() string {
+	return "string"
+}
+
 type boolValue bool
 
 func (b *boolValue) Set(val string) error {
@@ -29,6 +57,14 @@ func (b *boolValue) Set(val string) error {
 	return err
 }
 
+func (b *boolValue) String() string {
+	return strconv.FormatBool(bool(*b))
+}
+
+func (b *boolValue) Type() string {
+	return "bool"
+}
+
 type countValue int
 
 func (c *countValue) Set(_ string) error {
@@ -36,6 +72,14 @@ func (c *countValue) Set(_ string) error {
 	return nil
 }
 
+func (c *countValue) String() string {
+	return strconv.Itoa(int(*c))
+}
+
+func (c *countValue) Type() string {
+	return "count"
+}
+
 type intValue int
 
 func (i *intValue) Set(val string) error {
@@ -44,6 +88,14 @@ func (i *intValue) Set(val string) error {
 	return err
 }
 
+func (i *intValue) String() string {
+	return strconv.Itoa(int(*i))
+}
+
+func (i *intValue) Type() string {
+	return "int"
+}
+
 type durationValue time.Duration
 
 func (d *durationValue) Set(val string) error {
@@ -52,6 +104,14 @@ func (d *durationValue) Set(val string) error {
 	return err
 }
 
+func (d *durationValue) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *durationValue) Type() string {
+	return "duration"
+}
+
 type float64Value float64
 
 func (f *float64Value) Set(val string) error {
@@ -59,3 +119,319 @@ func (f *float64Value) Set(val string) error {
 	*f = float64Value(v)
 	return err
 }
+
+func (f *float64Value) String() string {
+	return strconv.FormatFloat(float64(*f), 'g', -1, 64)
+}
+
+func (f *float64Value) Type() string {
+	return "float64"
+}
+
+// splitValue splits a comma-separated value when split is true.
+func splitValue(val string, split bool) []string {
+	if !split {
+		return []string{val}
+	}
+	return strings.Split(val, ",")
+}
+
+// numberValue sets integer and float struct fields of any size, such as int64 or
+// float32, through reflection.
+type numberValue struct {
+	value reflect.Value
+}
+
+func (n *numberValue) Set(val string) error {
+	switch n.value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(val, n.value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		n.value.SetFloat(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(val, 10, n.value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		n.value.SetUint(v)
+	default:
+		v, err := strconv.ParseInt(val, 10, n.value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		n.value.SetInt(v)
+	}
+	return nil
+}
+
+func (n *numberValue) String() string {
+	return fmt.Sprint(n.value.Interface())
+}
+
+func (n *numberValue) Type() string {
+	return n.value.Kind().String()
+}
+
+// resetter is implemented by values accumulating across occurrences of a flag.
+// reset makes the next occurrence replace the current value instead.
+type resetter interface {
+	reset()
+}
+
+// resetValue resets a value accumulating across occurrences, so that a source
+// of higher priority replaces the values given by a lower priority one.
+func resetValue(value Value) {
+	if r, ok := value.(resetter); ok {
+		r.reset()
+	}
+}
+
+// stringSliceValue appends a value on every occurrence of a flag.
+// The initial value of the slice is considered as its default and
+// is replaced on the first occurrence.
+type stringSliceValue struct {
+	value *[]string
+	split bool
+	set   bool
+}
+
+func (s *stringSliceValue) Set(val string) error {
+	if !s.set {
+		*s.value, s.set = nil, true
+	}
+	*s.value = append(*s.value, splitValue(val, s.split)...)
+	return nil
+}
+
+func (s *stringSliceValue) String() string {
+	return "[" + strings.Join(*s.value, ",") + "]"
+}
+
+func (s *stringSliceValue) Type() string {
+	return "[]string"
+}
+
+func (s *stringSliceValue) reset() {
+	s.set = false
+}
+
+type intSliceValue struct {
+	value *[]int
+	split bool
+	set   bool
+}
+
+func (s *intSliceValue) Set(val string) error {
+	var vals []int
+	for _, str := range splitValue(val, s.split) {
+		v, err := strconv.Atoi(str)
+		if err != nil {
+			return err
+		}
+		vals = append(vals, v)
+	}
+	if !s.set {
+		*s.value, s.set = nil, true
+	}
+	*s.value = append(*s.value, vals...)
+	return nil
+}
+
+func (s *intSliceValue) String() string {
+	strs := make([]string, len(*s.value))
+	for i, v := range *s.value {
+		strs[i] = strconv.Itoa(v)
+	}
+	return "[" + strings.Join(strs, ",") + "]"
+}
+
+func (s *intSliceValue) Type() string {
+	return "[]int"
+}
+
+func (s *intSliceValue) reset() {
+	s.set = false
+}
+
+type float64SliceValue struct {
+	value *[]float64
+	split bool
+	set   bool
+}
+
+func (s *float64SliceValue) Set(val string) error {
+	var vals []float64
+	for _, str := range splitValue(val, s.split) {
+		v, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return err
+		}
+		vals = append(vals, v)
+	}
+	if !s.set {
+		*s.value, s.set = nil, true
+	}
+	*s.value = append(*s.value, vals...)
+	return nil
+}
+
+func (s *float64SliceValue) String() string {
+	strs := make([]string, len(*s.value))
+	for i, v := range *s.value {
+		strs[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return "[" + strings.Join(strs, ",") + "]"
+}
+
+func (s *float64SliceValue) Type() string {
+	return "[]float64"
+}
+
+func (s *float64SliceValue) reset() {
+	s.set = false
+}
+
+type durationSliceValue struct {
+	value *[]time.Duration
+	split bool
+	set   bool
+}
+
+func (s *durationSliceValue) Set(val string) error {
+	var vals []time.Duration
+	for _, str := range splitValue(val, s.split) {
+		v, err := time.ParseDuration(str)
+		if err != nil {
+			return err
+		}
+		vals = append(vals, v)
+	}
+	if !s.set {
+		*s.value, s.set = nil, true
+	}
+	*s.value = append(*s.value, vals...)
+	return nil
+}
+
+func (s *durationSliceValue) String() string {
+	strs := make([]string, len(*s.value))
+	for i, v := range *s.value {
+		strs[i] = v.String()
+	}
+	return "[" + strings.Join(strs, ",") + "]"
+}
+
+func (s *durationSliceValue) Type() string {
+	return "[]duration"
+}
+
+func (s *durationSliceValue) reset() {
+	s.set = false
+}
+
+// stringMapValue parses key=value pairs across repeated occurrences of a flag.
+// The initial value of the map is considered as its default and is replaced
+// on the first occurrence.
+type stringMapValue struct {
+	value  *map[string]string
+	unique bool
+	set    bool
+}
+
+func (m *stringMapValue) Set(val string) error {
+	i := strings.IndexByte(val, '=')
+	if i < 1 {
+		return errors.New("expected key=value pair")
+	}
+	if !m.set {
+		*m.value, m.set = make(map[string]string), true
+	}
+	key := val[:i]
+	if _, ok := (*m.value)[key]; ok && m.unique {
+		return fmt.Errorf("duplicate key %q", key)
+	}
+	(*m.value)[key] = val[i+1:]
+	return nil
+}
+
+func (m *stringMapValue) String() string {
+	pairs := make([]string, 0, len(*m.value))
+	for k, v := range *m.value {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return "[" + strings.Join(pairs, ",") + "]"
+}
+
+func (m *stringMapValue) Type() string {
+	return "map[string]string"
+}
+
+func (m *stringMapValue) reset() {
+	m.set = false
+}
+
+// enumValue restricts a string value to a fixed set of choices.
+type enumValue struct {
+	value   *string
+	choices []string
+}
+
+func (e *enumValue) Set(val string) error {
+	for _, choice := range e.choices {
+		if val == choice {
+			*e.value = val
+			return nil
+		}
+	}
+	return fmt.Errorf("allowed values are %s", strings.Join(e.choices, ", "))
+}
+
+func (e *enumValue) String() string {
+	return *e.value
+}
+
+func (e *enumValue) Type() string {
+	return strings.Join(e.choices, "|")
+}
+
+// textValue adapts a value implementing encoding.TextUnmarshaler, or
+// encoding.BinaryUnmarshaler such as url.URL, to the Value interface.
+type textValue struct {
+	value interface{}
+	typ   string
+}
+
+func (t *textValue) Set(val string) error {
+	if u, ok := t.value.(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(val))
+	}
+	return t.value.(encoding.BinaryUnmarshaler).UnmarshalBinary([]byte(val))
+}
+
+func (t *textValue) String() string {
+	elem := reflect.ValueOf(t.value).Elem()
+	if elem.IsZero() {
+		return ""
+	}
+	var text []byte
+	var err error
+	switch m := t.value.(type) {
+	case encoding.TextMarshaler:
+		text, err = m.MarshalText()
+	case encoding.BinaryMarshaler:
+		text, err = m.MarshalBinary()
+	default:
+		return fmt.Sprint(elem.Interface())
+	}
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+func (t *textValue) Type() string {
+	return t.typ
+}
@@ -58,3 +58,103 @@ func TestFloat64Value(t *testing.T) {
 	require.NoError(t, val.Set("3.14159265358979323846264338327950288419716939937510"))
 	require.Equal(t, 3.14159265358979323846264338327950288419716939937510, fvar)
 }
+
+func TestStringSliceValue(t *testing.T) {
+	svar := []string{"default"}
+	val := &stringSliceValue{value: &svar}
+
+	require.NoError(t, val.Set("a,b"))
+	require.NoError(t, val.Set("c"))
+	require.Equal(t, []string{"a,b", "c"}, svar)
+
+	val = &stringSliceValue{value: &svar, split: true}
+	require.NoError(t, val.Set("a,b"))
+	require.NoError(t, val.Set("c"))
+	require.Equal(t, []string{"a", "b", "c"}, svar)
+}
+
+func TestIntSliceValue(t *testing.T) {
+	var ivar []int
+	val := &intSliceValue{value: &ivar, split: true}
+
+	require.Error(t, val.Set("1,invalid"))
+	require.NoError(t, val.Set("1,2"))
+	require.NoError(t, val.Set("3"))
+	require.Equal(t, []int{1, 2, 3}, ivar)
+}
+
+func TestFloat64SliceValue(t *testing.T) {
+	var fvar []float64
+	val := &float64SliceValue{value: &fvar}
+
+	require.Error(t, val.Set("invalid"))
+	require.NoError(t, val.Set("1.5"))
+	require.NoError(t, val.Set("2.5"))
+	require.Equal(t, []float64{1.5, 2.5}, fvar)
+}
+
+func TestDurationSliceValue(t *testing.T) {
+	var dvar []time.Duration
+	val := &durationSliceValue{value: &dvar, split: true}
+
+	require.Error(t, val.Set("1s,invalid"))
+	require.NoError(t, val.Set("1s,5m"))
+	require.Equal(t, []time.Duration{time.Second, 5 * time.Minute}, dvar)
+}
+
+func TestStringMapValue(t *testing.T) {
+	mvar := map[string]string{"default": "ok"}
+	val := &stringMapValue{value: &mvar}
+
+	require.NoError(t, val.Set("env=prod"))
+	require.NoError(t, val.Set("team=core=ops"))
+	require.NoError(t, val.Set("env=dev"))
+	require.Equal(t, map[string]string{"env": "dev", "team": "core=ops"}, mvar)
+
+	require.EqualError(t, val.Set("env"), "expected key=value pair")
+	require.EqualError(t, val.Set("=prod"), "expected key=value pair")
+
+	val = &stringMapValue{value: &mvar, unique: true}
+	require.NoError(t, val.Set("env=prod"))
+	require.EqualError(t, val.Set("env=dev"), `duplicate key "env"`)
+}
+
+func TestEnumValue(t *testing.T) {
+	var svar string
+	val := &enumValue{value: &svar, choices: []string{"json", "yaml"}}
+
+	require.EqualError(t, val.Set("xml"), "allowed values are json, yaml")
+	require.NoError(t, val.Set("yaml"))
+	require.Equal(t, "yaml", svar)
+}
+
+func TestTypedValues(t *testing.T) {
+	str, b, c, i, d, f := "ok", true, 3, 8080, 5*time.Second, 3.14
+	strs, ints := []string{"a", "b"}, []int{1, 2}
+	floats, durations := []float64{1.5}, []time.Duration{time.Second}
+	labels, format := map[string]string{"team": "core", "env": "prod"}, "json"
+
+	fixtures := []struct {
+		value    TypedValue
+		str      string
+		typeName string
+	}{
+		{(*stringValue)(&str), "ok", "string"},
+		{(*boolValue)(&b), "true", "bool"},
+		{(*countValue)(&c), "3", "count"},
+		{(*intValue)(&i), "8080", "int"},
+		{(*durationValue)(&d), "5s", "duration"},
+		{(*float64Value)(&f), "3.14", "float64"},
+		{&stringSliceValue{value: &strs}, "[a,b]", "[]string"},
+		{&intSliceValue{value: &ints}, "[1,2]", "[]int"},
+		{&float64SliceValue{value: &floats}, "[1.5]", "[]float64"},
+		{&durationSliceValue{value: &durations}, "[1s]", "[]duration"},
+		{&stringMapValue{value: &labels}, "[env=prod,team=core]", "map[string]string"},
+		{&enumValue{value: &format, choices: []string{"json", "yaml"}}, "json", "json|yaml"},
+	}
+
+	for _, fixture := range fixtures {
+		require.Equal(t, fixture.str, fixture.value.String())
+		require.Equal(t, fixture.typeName, fixture.value.Type())
+	}
+}